@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
+)
+
+var usage = `
+cycles <command> [flags] [arguments]
+
+Rejewski's characteristic method against doubled message keys. Before
+1938 every message started with its three letter key typed twice at the
+daily ground setting, so the first and fourth, second and fifth, and
+third and sixth letters of the indicators were enciphered from the same
+key letter. The cycle structure of the resulting AD, BE and CF
+permutations does not depend on the plugboard and identifies the rotor
+order and ground setting.
+
+Commands:
+	catalogue [flags] <output file>   precompute the characteristic of every rotor order and position
+	match [flags] <indicator file>    print the daily keys matching a day's indicators
+	simulate [flags]                  produce a day's indicators for a known daily key
+
+Run "cycles <command> -h" for the flags of each command.
+`
+
+// Machine holds the daily settings the catalogue is computed for.
+type Machine struct {
+	Reflector string
+	Rings     []int
+}
+
+// Characteristic is the cycle structure of AD, BE and CF: for each
+// permutation, its cycle lengths in descending order.
+type Characteristic [3][]int
+
+// String formats the characteristic as e.g. "13 13/10 10 3 3/12 12 1 1".
+func (c Characteristic) String() string {
+	parts := make([]string, 3)
+	for i, lengths := range c {
+		values := make([]string, len(lengths))
+		for j, length := range lengths {
+			values[j] = strconv.Itoa(length)
+		}
+		parts[i] = strings.Join(values, " ")
+	}
+	return strings.Join(parts, "/")
+}
+
+// CycleLengths returns the lengths of the cycles of a permutation in
+// descending order.
+func CycleLengths(perm [26]int) []int {
+	var seen [26]bool
+	var lengths []int
+	for start := 0; start < 26; start++ {
+		if seen[start] {
+			continue
+		}
+		length := 0
+		for x := start; !seen[x]; x = perm[x] {
+			seen[x] = true
+			length++
+		}
+		lengths = append(lengths, length)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+	return lengths
+}
+
+// Products returns AD, BE and CF for a machine whose rotors sit at the
+// ground setting: the permutation taking each indicator letter to the
+// one three places later.
+func Products(e *enigma.Enigma) [3][26]int {
+	var perms [6][26]int
+	for i := range perms {
+		e.Step()
+		perms[i] = e.Permutation()
+	}
+	var products [3][26]int
+	for i := 0; i < 3; i++ {
+		for x := 0; x < 26; x++ {
+			products[i][x] = perms[i+3][perms[i][x]]
+		}
+	}
+	return products
+}
+
+// Characterise returns the characteristic of a set of products.
+func Characterise(products [3][26]int) Characteristic {
+	var c Characteristic
+	for i := range products {
+		c[i] = CycleLengths(products[i])
+	}
+	return c
+}
+
+// IndicatorProducts builds AD, BE and CF from a day's six letter
+// indicators. Every letter must appear in each of the first three
+// positions for the products to be complete.
+func IndicatorProducts(indicators []string) ([3][26]int, error) {
+	var products [3][26]int
+	for i := range products {
+		for x := range products[i] {
+			products[i][x] = -1
+		}
+	}
+	for _, indicator := range indicators {
+		for i := 0; i < 3; i++ {
+			from := enigma.CharToIndex(indicator[i])
+			to := enigma.CharToIndex(indicator[i+3])
+			if products[i][from] != -1 && products[i][from] != to {
+				return products, fmt.Errorf("indicator %s contradicts an earlier one", indicator)
+			}
+			products[i][from] = to
+		}
+	}
+	for i, name := range []string{"AD", "BE", "CF"} {
+		var missing []byte
+		for x := 0; x < 26; x++ {
+			if products[i][x] == -1 {
+				missing = append(missing, enigma.IndexToChar(x))
+			}
+		}
+		if len(missing) > 0 {
+			return products, fmt.Errorf("%s is incomplete, no indicator has %s in position %d", name, missing, i+1)
+		}
+	}
+	return products, nil
+}
+
+// ReadIndicators reads every six letter word from a file.
+func ReadIndicators(name string) ([]string, error) {
+	bytes, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var indicators []string
+	for _, word := range strings.Fields(strings.ToUpper(string(bytes))) {
+		if len(word) == 6 && strings.Trim(word, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+			indicators = append(indicators, word)
+		}
+	}
+	return indicators, nil
+}
+
+// NewMachine builds an Enigma with an empty plugboard at a ground setting.
+func NewMachine(machine Machine, order []string, ground string) *enigma.Enigma {
+	config := make([]enigma.RotorConfig, len(order))
+	for i, id := range order {
+		config[i] = enigma.RotorConfig{ID: id, Start: ground[i], Ring: machine.Rings[i]}
+	}
+	return enigma.NewEnigma(config, machine.Reflector, *enigma.NewPlugboard(nil))
+}
+
+// Catalogue calls fn with the characteristic of every rotor order from the
+// pool at every ground setting.
+func Catalogue(machine Machine, pool []string, fn func(order []string, ground string, c Characteristic)) {
+	for _, order := range enigma.RotorOrders(pool) {
+		ground := []byte("AAA")
+		for l := 0; l < 26; l++ {
+			for m := 0; m < 26; m++ {
+				for r := 0; r < 26; r++ {
+					ground[0], ground[1], ground[2] = enigma.IndexToChar(l), enigma.IndexToChar(m), enigma.IndexToChar(r)
+					e := NewMachine(machine, order, string(ground))
+					fn(order, string(ground), Characterise(Products(e)))
+				}
+			}
+		}
+	}
+}
+
+// catalogueLine formats one catalogue entry, e.g. "I II III ABC 13 13/...".
+func catalogueLine(order []string, ground string, c Characteristic) string {
+	return fmt.Sprintf("%s %s %s", strings.Join(order, " "), ground, c)
+}
+
+// catalogueHeader formats the first line of a catalogue, naming the
+// machine it was computed for, e.g. "# reflector B rings 1 1 1".
+func catalogueHeader(machine Machine) string {
+	rings := make([]string, len(machine.Rings))
+	for i, ring := range machine.Rings {
+		rings[i] = strconv.Itoa(ring)
+	}
+	return fmt.Sprintf("# reflector %s rings %s", machine.Reflector, strings.Join(rings, " "))
+}
+
+// ReadCatalogue calls fn with each entry of a catalogue whose
+// characteristic is want and whose rotors all come from the pool. The
+// catalogue must have been computed for machine: a catalogue for another
+// reflector or ring setting would match the wrong daily keys.
+func ReadCatalogue(r io.Reader, machine Machine, pool []string, want string, fn func(order []string, ground string)) error {
+	inPool := make(map[string]bool)
+	for _, id := range pool {
+		inPool[id] = true
+	}
+
+	br := bufio.NewReader(r)
+	header := true
+	for {
+		line, err := br.ReadString('\n')
+		if header {
+			// Older catalogues wrote the rings as "[1 1 1]"
+			got := strings.NewReplacer("[", "", "]", "").Replace(strings.TrimSpace(line))
+			if !strings.HasPrefix(got, "#") {
+				return fmt.Errorf("catalogue has no \"# reflector ... rings ...\" header")
+			}
+			if expected := catalogueHeader(machine); strings.Join(strings.Fields(got), " ") != expected {
+				return fmt.Errorf("catalogue is for %q, not %q", strings.TrimSpace(got[1:]), expected[2:])
+			}
+			header = false
+		} else if fields := strings.Fields(line); len(fields) > 4 && !strings.HasPrefix(line, "#") {
+			order := fields[0:3]
+			if inPool[order[0]] && inPool[order[1]] && inPool[order[2]] && strings.Join(fields[4:], " ") == want {
+				fn(order, fields[3])
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func parseMachine(flags *flag.FlagSet) (*Machine, *string) {
+	reflector := flags.String("reflector", "B", "reflector ID")
+	rings := flags.String("rings", "1 1 1", "ring settings")
+	pool := flags.String("rotors", "I II III IV V", "rotors to choose the rotor order from")
+	flags.Parse(os.Args[2:])
+
+	if enigma.HistoricReflectors.GetByID(*reflector) == nil {
+		log.Fatalf("unknown reflector %q", *reflector)
+	}
+	checkRotors(strings.Fields(*pool))
+
+	machine := &Machine{Reflector: *reflector}
+	for _, ring := range strings.Fields(*rings) {
+		value, err := strconv.Atoi(ring)
+		if err != nil || value < 1 || value > 26 {
+			log.Fatalf("invalid ring setting %q", ring)
+		}
+		machine.Rings = append(machine.Rings, value)
+	}
+	if len(machine.Rings) != 3 {
+		log.Fatal("expected 3 ring settings")
+	}
+	return machine, pool
+}
+
+// checkRotors exits on any ID that is not a historic rotor.
+func checkRotors(ids []string) {
+	for _, id := range ids {
+		if enigma.HistoricRotors.GetByID(id) == nil {
+			log.Fatalf("unknown rotor %q", id)
+		}
+	}
+}
+
+func catalogue() {
+	flags := flag.NewFlagSet("catalogue", flag.ExitOnError)
+	machine, pool := parseMachine(flags)
+	if flags.NArg() != 1 {
+		log.Fatal("usage: cycles catalogue [flags] <output file>")
+	}
+
+	f, err := os.Create(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	fmt.Fprintln(w, catalogueHeader(*machine))
+	Catalogue(*machine, strings.Fields(*pool), func(order []string, ground string, c Characteristic) {
+		fmt.Fprintln(w, catalogueLine(order, ground, c))
+	})
+}
+
+func match() {
+	flags := flag.NewFlagSet("match", flag.ExitOnError)
+	file := flags.String("catalogue", "", "catalogue file from \"cycles catalogue\" with the same -reflector and -rings; entries outside -rotors are skipped (default compute on the fly)")
+	machine, pool := parseMachine(flags)
+	if flags.NArg() != 1 {
+		log.Fatal("usage: cycles match [flags] <indicator file>")
+	}
+
+	indicators, err := ReadIndicators(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	products, err := IndicatorProducts(indicators)
+	if err != nil {
+		log.Fatal(err)
+	}
+	want := Characterise(products).String()
+	fmt.Printf("characteristic %s from %d indicators\n", want, len(indicators))
+
+	found := 0
+	if *file == "" {
+		Catalogue(*machine, strings.Fields(*pool), func(order []string, ground string, c Characteristic) {
+			if c.String() == want {
+				fmt.Printf("rotors %s ground %s\n", strings.Join(order, " "), ground)
+				found++
+			}
+		})
+	} else {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		err = ReadCatalogue(f, *machine, strings.Fields(*pool), want, func(order []string, ground string) {
+			fmt.Printf("rotors %s ground %s\n", strings.Join(order, " "), ground)
+			found++
+		})
+		if err != nil {
+			log.Fatalf("%s: %v", *file, err)
+		}
+	}
+	if found == 0 {
+		fmt.Println("no daily key matches")
+		os.Exit(1)
+	}
+}
+
+func simulate() {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	order := flags.String("order", "I II III", "rotor order")
+	ground := flags.String("ground", "AAA", "ground setting")
+	plugs := flags.String("plugboard", "", "plugboard pairs, e.g. \"AB CD\"")
+	count := flags.Int("n", 100, "number of indicators")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "random seed for the message keys")
+	machine, _ := parseMachine(flags)
+
+	plugboard, err := enigma.ParsePlugboard(*plugs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rotors := strings.Fields(*order)
+	if len(rotors) != 3 || len(*ground) != 3 {
+		log.Fatal("expected 3 rotors and a 3 letter ground setting")
+	}
+	checkRotors(rotors)
+
+	rng := rand.New(rand.NewSource(*seed))
+	for i := 0; i < *count; i++ {
+		key := make([]byte, 3)
+		for j := range key {
+			key[j] = enigma.IndexToChar(rng.Intn(26))
+		}
+		e := NewMachine(*machine, rotors, strings.ToUpper(*ground))
+		e.Plugboard = *plugboard
+		fmt.Println(e.EncodeString(string(key) + string(key)))
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "catalogue":
+		catalogue()
+	case "match":
+		match()
+	case "simulate":
+		simulate()
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
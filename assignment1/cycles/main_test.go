@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var testMachine = Machine{Reflector: "B", Rings: []int{1, 1, 1}}
+
+// testCatalogue has two entries with the characteristic testWant, one
+// of them using rotor V.
+const testWant = "13 13/10 10 3 3/12 12 1 1"
+
+const testEntries = `I II III AAA 13 13/10 10 3 3/12 12 1 1
+I II III AAB 9 9 4 4/13 13/6 6 5 5 1 1
+II V I QRS 13 13/10 10 3 3/12 12 1 1
+`
+
+func readTestCatalogue(catalogue string, machine Machine, pool string) ([]string, error) {
+	var got []string
+	err := ReadCatalogue(strings.NewReader(catalogue), machine, strings.Fields(pool), testWant, func(order []string, ground string) {
+		got = append(got, strings.Join(order, " ")+" "+ground)
+	})
+	return got, err
+}
+
+func TestReadCatalogue(t *testing.T) {
+	for _, c := range []struct {
+		header string
+		pool   string
+		want   string
+	}{
+		{catalogueHeader(testMachine), "I II III IV V", "I II III AAA,II V I QRS"},
+		{catalogueHeader(testMachine), "I II III IV", "I II III AAA"},
+		{catalogueHeader(testMachine), "I II IV V", "II V I QRS"},
+		{catalogueHeader(testMachine), "III IV V", ""},
+		{"# reflector B rings [1 1 1]", "I II III IV V", "I II III AAA,II V I QRS"},
+		{"#  reflector B   rings 1 1 1 ", "I II III", "I II III AAA"},
+	} {
+		got, err := readTestCatalogue(c.header+"\n"+testEntries, testMachine, c.pool)
+		if err != nil {
+			t.Errorf("%q, -rotors %q: %v", c.header, c.pool, err)
+		} else if strings.Join(got, ",") != c.want {
+			t.Errorf("%q, -rotors %q: matched %q, want %q", c.header, c.pool, got, c.want)
+		}
+	}
+}
+
+func TestReadCatalogueWrongMachine(t *testing.T) {
+	for _, c := range []struct {
+		catalogue string
+		machine   Machine
+	}{
+		{catalogueHeader(testMachine) + "\n" + testEntries, Machine{Reflector: "C", Rings: []int{1, 1, 1}}},
+		{catalogueHeader(testMachine) + "\n" + testEntries, Machine{Reflector: "B", Rings: []int{1, 2, 1}}},
+		{"# reflector C rings 1 1 1\n" + testEntries, testMachine},
+		{testEntries, testMachine},
+		{"", testMachine},
+	} {
+		if got, err := readTestCatalogue(c.catalogue, c.machine, "I II III IV V"); err == nil {
+			t.Errorf("machine %v accepted a catalogue starting %q, matched %q", c.machine, firstLine(c.catalogue), got)
+		}
+	}
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
@@ -1,4 +1,4 @@
-package main
+package enigma
 
 import "bytes"
 
@@ -20,15 +20,15 @@ type RotorConfig struct {
 }
 
 // NewEnigma is the Enigma constructor, accepting an array of RotorConfig objects
-// for rotors, a reflector ID/name, and an array of plugboard pairs.
-func NewEnigma(rotorConfiguration []RotorConfig, refID string, plugs string) *Enigma {
+// for rotors, a reflector ID/name, and a plugboard.
+func NewEnigma(rotorConfiguration []RotorConfig, refID string, plugboard Plugboard) *Enigma {
 	rotors := make([]*Rotor, len(rotorConfiguration))
 	for i, configuration := range rotorConfiguration {
 		rotors[i] = HistoricRotors.GetByID(configuration.ID)
 		rotors[i].Offset = CharToIndex(configuration.Start)
 		rotors[i].Ring = configuration.Ring - 1
 	}
-	return &Enigma{*HistoricReflectors.GetByID(refID), *NewPlugboardAlternate(plugs), rotors}
+	return &Enigma{*HistoricReflectors.GetByID(refID), plugboard, rotors}
 }
 
 func (e *Enigma) moveRotors() {
@@ -52,12 +52,16 @@ func (e *Enigma) moveRotors() {
 	farRight.move(1)
 }
 
-// EncodeChar encodes a single character.
-func (e *Enigma) EncodeChar(letter byte) byte {
+// Step advances the rotors as a key press would, without encoding
+// a letter.
+func (e *Enigma) Step() {
 	e.moveRotors()
+}
 
-	letterIndex := CharToIndex(letter)
-	letterIndex = e.Plugboard[letterIndex]
+// Map sends a letter index through the plugboard, the rotors and the
+// reflector at the current rotor positions, without moving the rotors.
+func (e *Enigma) Map(letterIndex int) int {
+	letterIndex = e.Plugboard.Apply(letterIndex)
 
 	for i := len(e.Rotors) - 1; i >= 0; i-- {
 		letterIndex = e.Rotors[i].Step(letterIndex, false)
@@ -69,10 +73,23 @@ func (e *Enigma) EncodeChar(letter byte) byte {
 		letterIndex = e.Rotors[i].Step(letterIndex, true)
 	}
 
-	letterIndex = e.Plugboard[letterIndex]
-	letter = IndexToChar(letterIndex)
+	return e.Plugboard.Apply(letterIndex)
+}
+
+// Permutation returns the substitution performed at the current rotor
+// positions, i.e. Map for every letter index.
+func (e *Enigma) Permutation() [26]int {
+	var perm [26]int
+	for i := range perm {
+		perm[i] = e.Map(i)
+	}
+	return perm
+}
 
-	return letter
+// EncodeChar encodes a single character.
+func (e *Enigma) EncodeChar(letter byte) byte {
+	e.moveRotors()
+	return IndexToChar(e.Map(CharToIndex(letter)))
 }
 
 // EncodeString encodes a string.
@@ -0,0 +1,121 @@
+package enigma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxCables is the largest number of plug cables a Plugboard may hold.
+// Wartime procedure used 10 cables, and 13 is the most that fit on
+// the 26 sockets.
+const MaxCables = 13
+
+// Plugboard is a two-way mapping between characters modifying the
+// encoding procedure of the Enigma machine.
+type Plugboard [26]int
+
+// NewPlugboard is the plugboard constructor accepting an array
+// of two-symbol strings representing plug pairs.
+func NewPlugboard(pairs []string) *Plugboard {
+	p := Plugboard{}
+	for i := 0; i < 26; i++ {
+		p[i] = i
+	}
+	for _, pair := range pairs {
+		if len(pair) > 0 {
+			var intFirst = CharToIndex(pair[0])
+			var intSecond = CharToIndex(pair[1])
+			p[intFirst] = intSecond
+			p[intSecond] = intFirst
+		}
+	}
+	return &p
+}
+
+// ParsePlugboard reads a plugboard written in pair notation, e.g.
+// "AB CD EF". Letters may only be plugged once and at most MaxCables
+// pairs are accepted; an empty string is an empty plugboard.
+func ParsePlugboard(s string) (*Plugboard, error) {
+	pairs := strings.Fields(strings.ToUpper(s))
+	if len(pairs) > MaxCables {
+		return nil, fmt.Errorf("plugboard %q uses %d cables, at most %d allowed", s, len(pairs), MaxCables)
+	}
+	p := NewPlugboard(nil)
+	for _, pair := range pairs {
+		if len(pair) != 2 || pair[0] < 'A' || pair[0] > 'Z' || pair[1] < 'A' || pair[1] > 'Z' {
+			return nil, fmt.Errorf("invalid plugboard pair %q", pair)
+		}
+		first, second := CharToIndex(pair[0]), CharToIndex(pair[1])
+		if first == second || p.IsPlugged(first) || p.IsPlugged(second) {
+			return nil, fmt.Errorf("plugboard pair %q reuses a letter", pair)
+		}
+		p.Swap(first, second)
+	}
+	return p, nil
+}
+
+// Apply returns the letter index a goes to through the plugboard.
+func (p *Plugboard) Apply(a int) int {
+	return p[a]
+}
+
+// IsPlugged reports whether a cable is connected to letter a.
+func (p *Plugboard) IsPlugged(a int) bool {
+	return p[a] != a
+}
+
+// Cables returns the number of cables in use.
+func (p *Plugboard) Cables() int {
+	n := 0
+	for i := 0; i < 26; i++ {
+		if p[i] > i {
+			n++
+		}
+	}
+	return n
+}
+
+// Unplug removes the cable connected to letter a, if any.
+func (p *Plugboard) Unplug(a int) {
+	b := p[a]
+	p[a] = a
+	p[b] = b
+}
+
+// Swap connects letters a and b with a cable, first unplugging any
+// cables already attached to either of them. It returns false and
+// leaves the plugboard untouched if a and b are the same letter or
+// the new cable would exceed MaxCables.
+func (p *Plugboard) Swap(a, b int) bool {
+	if a == b {
+		return false
+	}
+	if p[a] == b {
+		return true
+	}
+	if !p.IsPlugged(a) && !p.IsPlugged(b) && p.Cables() >= MaxCables {
+		return false
+	}
+	p.Unplug(a)
+	p.Unplug(b)
+	p[a] = b
+	p[b] = a
+	return true
+}
+
+// Pairs returns the plugged pairs in alphabetical order, in the same
+// form accepted by NewPlugboard.
+func (p *Plugboard) Pairs() []string {
+	pairs := make([]string, 0, MaxCables)
+	for i := 0; i < 26; i++ {
+		if p[i] > i {
+			pairs = append(pairs, string([]byte{IndexToChar(i), IndexToChar(p[i])}))
+		}
+	}
+	return pairs
+}
+
+// String formats the plugboard in pair notation, e.g. "AB CD EF".
+func (p *Plugboard) String() string {
+	return strings.Join(p.Pairs(), " ")
+}
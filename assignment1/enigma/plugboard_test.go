@@ -0,0 +1,139 @@
+package enigma
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePlugboard(t *testing.T) {
+	for _, c := range []struct {
+		in     string
+		want   string
+		cables int
+	}{
+		{"", "", 0},
+		{"   ", "", 0},
+		{"AB", "AB", 1},
+		{"ba", "AB", 1},
+		{"ZQ AB  cd", "AB CD QZ", 3},
+		{"AB CD EF GH IJ KL MN OP QR ST UV WX YZ", "AB CD EF GH IJ KL MN OP QR ST UV WX YZ", 13},
+	} {
+		p, err := ParsePlugboard(c.in)
+		if err != nil {
+			t.Errorf("ParsePlugboard(%q): %v", c.in, err)
+			continue
+		}
+		if got := p.String(); got != c.want {
+			t.Errorf("ParsePlugboard(%q) = %q, want %q", c.in, got, c.want)
+		}
+		if got := p.Cables(); got != c.cables {
+			t.Errorf("ParsePlugboard(%q) has %d cables, want %d", c.in, got, c.cables)
+		}
+		for a := 0; a < 26; a++ {
+			if p.Apply(p.Apply(a)) != a {
+				t.Errorf("ParsePlugboard(%q) is not an involution at %c", c.in, IndexToChar(a))
+			}
+		}
+	}
+}
+
+func TestParsePlugboardErrors(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want string
+	}{
+		{"AB CD EF GH IJ KL MN OP QR ST UV WX YZ AC", "at most 13 allowed"},
+		{"AB AC", "reuses a letter"},
+		{"AB CB", "reuses a letter"},
+		{"AA", "reuses a letter"},
+		{"A", "invalid plugboard pair"},
+		{"ABC", "invalid plugboard pair"},
+		{"A1", "invalid plugboard pair"},
+		{"A-B", "invalid plugboard pair"},
+	} {
+		p, err := ParsePlugboard(c.in)
+		if err == nil {
+			t.Errorf("ParsePlugboard(%q) = %q, want an error", c.in, p)
+		} else if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("ParsePlugboard(%q): %v, want %q", c.in, err, c.want)
+		}
+	}
+}
+
+func TestSwapAndUnplug(t *testing.T) {
+	p := NewPlugboard(nil)
+	if p.Swap(0, 0) {
+		t.Error("Swap(A, A) succeeded")
+	}
+	if !p.Swap(0, 1) || p.String() != "AB" {
+		t.Fatalf("Swap(A, B) gave %q, want AB", p)
+	}
+	if !p.Swap(1, 0) || p.String() != "AB" {
+		t.Errorf("Swap(B, A) again gave %q, want AB", p)
+	}
+
+	// Moving a plugged letter takes its old cable with it
+	if !p.Swap(0, 2) || p.String() != "AC" || p.IsPlugged(1) {
+		t.Errorf("Swap(A, C) gave %q, want AC with B free", p)
+	}
+	p.Swap(3, 4)
+	if !p.Swap(2, 3) || p.String() != "CD" || p.IsPlugged(0) || p.IsPlugged(4) {
+		t.Errorf("Swap(C, D) of two plugged letters gave %q, want CD", p)
+	}
+
+	p.Unplug(3)
+	if p.String() != "" || p.Cables() != 0 {
+		t.Errorf("Unplug(D) left %q", p)
+	}
+	p.Unplug(3)
+	if p.String() != "" {
+		t.Errorf("Unplug of a free letter gave %q", p)
+	}
+}
+
+func TestSwapMaxCables(t *testing.T) {
+	p, err := ParsePlugboard("AB CD EF GH IJ KL MN OP QR ST UV WX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Cables() != MaxCables-1 {
+		t.Fatalf("%d cables, want %d", p.Cables(), MaxCables-1)
+	}
+	if !p.Swap(CharToIndex('Y'), CharToIndex('Z')) || p.Cables() != MaxCables {
+		t.Fatalf("could not add cable %d: %q", MaxCables, p)
+	}
+	full := p.String()
+
+	// A full board can still rewire its own letters
+	if !p.Swap(CharToIndex('A'), CharToIndex('C')) || p.Cables() != MaxCables-1 {
+		t.Errorf("Swap(A, C) on a full board gave %q", p)
+	}
+	p, _ = ParsePlugboard(full)
+	p.Unplug(CharToIndex('Y'))
+	p.Swap(CharToIndex('A'), CharToIndex('Y'))
+	if p.Cables() != MaxCables-1 || p.Apply(CharToIndex('A')) != CharToIndex('Y') {
+		t.Errorf("Swap(A, Y) gave %q", p)
+	}
+	p.Swap(CharToIndex('B'), CharToIndex('Z'))
+	if p.Cables() != MaxCables {
+		t.Fatalf("%d cables, want %d: %q", p.Cables(), MaxCables, p)
+	}
+	before := p.String()
+	if p.Swap(CharToIndex('A'), CharToIndex('A')) || p.String() != before {
+		t.Errorf("Swap(A, A) changed a full board to %q", p)
+	}
+}
+
+func TestPairs(t *testing.T) {
+	p := NewPlugboard([]string{"ZA", "", "MC"})
+	pairs := p.Pairs()
+	if strings.Join(pairs, ",") != "AZ,CM" {
+		t.Errorf("Pairs() = %q, want [AZ CM]", pairs)
+	}
+	if q := NewPlugboard(pairs); *q != *p {
+		t.Errorf("NewPlugboard(Pairs()) = %q, want %q", q, p)
+	}
+	if len(NewPlugboard(nil).Pairs()) != 0 {
+		t.Error("empty plugboard has pairs")
+	}
+}
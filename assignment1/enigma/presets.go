@@ -1,4 +1,4 @@
-package main
+package enigma
 
 // HistoricRotors match the original Enigma configurations, including the
 // notches. "Beta" and "Gamma" are additional rotors used in M4
@@ -25,3 +25,18 @@ var HistoricReflectors = Reflectors{
 	*NewReflector("ENKQAUYWJICOPBLMDXZVFTHRGS", "B-thin"),
 	*NewReflector("RDOBJNTKVEHMLFCWZAXGYIPSUQ", "C-thin"),
 }
+
+// RotorOrders lists every ordered choice of three rotors from the pool.
+func RotorOrders(pool []string) [][]string {
+	var orders [][]string
+	for _, left := range pool {
+		for _, middle := range pool {
+			for _, right := range pool {
+				if left != middle && left != right && middle != right {
+					orders = append(orders, []string{left, middle, right})
+				}
+			}
+		}
+	}
+	return orders
+}
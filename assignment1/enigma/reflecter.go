@@ -1,4 +1,4 @@
-package main
+package enigma
 
 // Reflector is used to reverse a signal inside the Enigma: the current
 // goes from the keys through the rotors to the reflector, then it is
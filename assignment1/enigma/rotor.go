@@ -1,4 +1,4 @@
-package main
+package enigma
 
 // Rotor is the device performing letter substitutions inside
 // the Enigma machine. Rotors can be put in different positions,
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -8,6 +9,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
 )
 
 // CLIDefaults is the defaults for the Engima Rotor.
@@ -22,11 +25,11 @@ var CLIDefaults = struct {
 	Ring:      "1 1 1 16",
 	Position:  "A A B Q",
 	Rotors:    "I II IV III",
-	Plugboard: "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	Plugboard: "",
 }
 
 // SetDefaultsForEnigmaMachine sets the defaults for the Engima Machine and returns an Engima Machine.
-func SetDefaultsForEnigmaMachine() *Enigma {
+func SetDefaultsForEnigmaMachine() *enigma.Enigma {
 
 	rotorArray := strings.Split(CLIDefaults.Rotors, " ")
 	var ringArray []int = make([]int, len(strings.Split(CLIDefaults.Ring, " ")))
@@ -36,24 +39,25 @@ func SetDefaultsForEnigmaMachine() *Enigma {
 		ringArray[idx], _ = strconv.Atoi(val)
 	}
 
-	config := make([]RotorConfig, len(rotorArray))
+	config := make([]enigma.RotorConfig, len(rotorArray))
 	for index, rotor := range rotorArray {
 		ring := ringArray[index]
 		value := posArray[index][0]
-		config[index] = RotorConfig{ID: rotor, Start: value, Ring: ring}
+		config[index] = enigma.RotorConfig{ID: rotor, Start: value, Ring: ring}
 	}
 
-	//plugboards := strings.Split(CLIDefaults.Plugboard, " ")
-	plugboards := string(CLIDefaults.Plugboard)
-	e := NewEnigma(config, CLIDefaults.Reflector, plugboards)
+	plugboard, err := enigma.ParsePlugboard(CLIDefaults.Plugboard)
+	if err != nil {
+		log.Fatal(err)
+	}
+	e := enigma.NewEnigma(config, CLIDefaults.Reflector, *plugboard)
 
 	return e
 }
 
 // ReadFileContents returns the contents of a file
-func ReadFileContents() string {
-	args := os.Args[1]
-	file, err := os.Open(args)
+func ReadFileContents(name string) string {
+	file, err := os.Open(name)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -64,33 +68,12 @@ func ReadFileContents() string {
 	return string(bytes)
 }
 
-// SwapCharacters swaps char1 and char2 in Plugboard
-func SwapCharacters(char1 string, char2 string, plugboard string) string {
-
-	plugboard = strings.Replace(plugboard, char1, ",", 1)
-	plugboard = strings.Replace(plugboard, char2, char1, 1)
-	plugboard = strings.Replace(plugboard, ",", char2, 1)
-
-	return plugboard
-}
-
-// SwapCharactersFast is a faster implementation of SwapCharacters
-func SwapCharactersFast(char1 string, char2 string, plugboard string) string {
-
-	index1 := strings.Index(plugboard, char1)
-	index2 := strings.Index(plugboard, char2)
-	plugboard = plugboard[0:index1] + char2 + plugboard[index1+1:]
-	plugboard = plugboard[0:index2] + char1 + plugboard[index2+1:]
-
-	return plugboard
-}
-
 // SetEnigmaAndGetScore changes the plugboard and returns the IOC of the decoded plaintext
-func SetEnigmaAndGetScore(plugboard string, ciphertext string, function string, m map[string]float64) float64 {
+func SetEnigmaAndGetScore(plugboard enigma.Plugboard, ciphertext string, function string, m map[string]float64) float64 {
 
-	CLIDefaults.Plugboard = plugboard
-	enigma := SetDefaultsForEnigmaMachine()
-	decoded := enigma.EncodeString(ciphertext)
+	e := SetDefaultsForEnigmaMachine()
+	e.Plugboard = plugboard
+	decoded := e.EncodeString(ciphertext)
 	score := float64(0)
 	if function == "ioc" {
 		score = CalculateIOC(decoded)
@@ -100,17 +83,20 @@ func SetEnigmaAndGetScore(plugboard string, ciphertext string, function string,
 	return score
 }
 
-// IteratePlugboard iterates through the plugboard once
-func IteratePlugboard(max float64, plugboard string, ciphertext string, function string, m map[string]float64) string {
-	base := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+// IteratePlugboard iterates through the plugboard once. Every candidate
+// it keeps satisfies the constraints, and once the plugboard has enough
+// cables it is never traded for one with fewer.
+func IteratePlugboard(max float64, plugboard enigma.Plugboard, ciphertext string, function string, m map[string]float64, c *SteckerConstraints) enigma.Plugboard {
 
-	hash := make(map[string]bool)
+	hash := make(map[enigma.Plugboard]bool)
 
 	// Two Loops. Outer Loop iterates through the entire range of letters
 	for i := 0; i < 26; i++ {
 
-		actual1 := string(base[i])
-		current1 := string(plugboard[i])
+		// Known plugs are never rewired
+		if c.Locked(i) {
+			continue
+		}
 
 		// temp stores the best possible plugboard for that loop
 		temp := plugboard
@@ -119,42 +105,54 @@ func IteratePlugboard(max float64, plugboard string, ciphertext string, function
 		// Inner Loop iterates through from that character to the end
 		for j := i + 1; j < 26; j++ {
 
-			plugboard = def
-
-			actual2 := string(base[j])
-			current2 := string(plugboard[j])
+			if c.Locked(j) {
+				continue
+			}
 
-			// We swap everything back to it's initial positions
-			plugboard = SwapCharactersFast(actual1, current1, plugboard)
-			plugboard = SwapCharactersFast(actual2, current2, plugboard)
+			partner1 := def.Apply(i)
+			partner2 := def.Apply(j)
 
-			// Now we can try 4 different plugboard alternatives
+			// Now we can try the different ways of (re)wiring i and j
 			// We have to find the best alternative from these
+			values := []enigma.Plugboard{}
 
-			values := []string{}
-			temp1 := SwapCharactersFast(actual1, current1, plugboard)
-			temp2 := SwapCharactersFast(actual1, current2, plugboard)
-			temp3 := SwapCharactersFast(actual2, current1, plugboard)
-			temp4 := SwapCharactersFast(actual2, current2, plugboard)
-
-			if hash[temp1] != true {
-				hash[temp1] = true
+			// Plug i into j, dropping their old cables
+			temp1 := def
+			if temp1.Swap(i, j) {
 				values = append(values, temp1)
 			}
-			if hash[temp2] != true {
-				hash[temp2] = true
+
+			// Unplug i and j if they are connected to each other
+			if partner1 == j {
+				temp2 := def
+				temp2.Unplug(i)
 				values = append(values, temp2)
 			}
-			if hash[temp3] != true {
-				hash[temp3] = true
+
+			// Plug i into j and their old partners into each other
+			if partner1 != i && partner2 != j && partner1 != j && !c.Locked(partner1) && !c.Locked(partner2) {
+				temp3 := def
+				temp3.Swap(i, j)
+				temp3.Swap(partner1, partner2)
 				values = append(values, temp3)
 			}
-			if hash[temp4] != true {
-				hash[temp4] = true
+
+			// Unplug both i and j
+			if partner1 != i || partner2 != j {
+				temp4 := def
+				temp4.Unplug(i)
+				temp4.Unplug(j)
 				values = append(values, temp4)
 			}
 
 			for _, value := range values {
+				if hash[value] {
+					continue
+				}
+				hash[value] = true
+				if !c.Allows(&value) || (c.Complete(&def) && !c.Complete(&value)) {
+					continue
+				}
 				score := SetEnigmaAndGetScore(value, ciphertext, function, m)
 				if score > max {
 					temp = value
@@ -167,17 +165,56 @@ func IteratePlugboard(max float64, plugboard string, ciphertext string, function
 	return plugboard
 }
 
-// HillClimbAttack performs the HillClimb Attack
-func HillClimbAttack(ciphertext string, m map[string]float64) (string, float64, float64) {
+// FillPlugboard greedily adds the best scoring cable between two free
+// letters until the plugboard has MinCables cables.
+func FillPlugboard(plugboard enigma.Plugboard, ciphertext string, function string, m map[string]float64, c *SteckerConstraints) enigma.Plugboard {
+
+	for !c.Complete(&plugboard) {
+		best := plugboard
+		max := math.Inf(-1)
+		for i := 0; i < 26; i++ {
+			for j := i + 1; j < 26; j++ {
+				if plugboard.IsPlugged(i) || plugboard.IsPlugged(j) {
+					continue
+				}
+				value := plugboard
+				if !value.Swap(i, j) || !c.Allows(&value) {
+					continue
+				}
+				score := SetEnigmaAndGetScore(value, ciphertext, function, m)
+				if score > max {
+					best = value
+					max = score
+				}
+			}
+		}
+		if best == plugboard {
+			break
+		}
+		plugboard = best
+	}
+	return plugboard
+}
+
+// HillClimbAttack performs the HillClimb Attack, starting from the known
+// plugs and keeping to the cable count given by the constraints.
+func HillClimbAttack(ciphertext string, m map[string]float64, c *SteckerConstraints) (enigma.Plugboard, float64, float64) {
 
 	// Initial Attack Based on IOC Score
-	base := string("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	base := c.Known
 	score := SetEnigmaAndGetScore(base, ciphertext, "ioc", m)
-	plugboard := IteratePlugboard(score, base, ciphertext, "ioc", m)
+	plugboard := IteratePlugboard(score, base, ciphertext, "ioc", m, c)
 
 	//Second Attack Based on Trigram Score
 	score = SetEnigmaAndGetScore(plugboard, ciphertext, "trigram", m)
-	plugboard = IteratePlugboard(score, plugboard, ciphertext, "trigram", m)
+	plugboard = IteratePlugboard(score, plugboard, ciphertext, "trigram", m, c)
+
+	// Top up to the required number of cables and climb once more
+	if !c.Complete(&plugboard) {
+		plugboard = FillPlugboard(plugboard, ciphertext, "trigram", m, c)
+		score = SetEnigmaAndGetScore(plugboard, ciphertext, "trigram", m)
+		plugboard = IteratePlugboard(score, plugboard, ciphertext, "trigram", m, c)
+	}
 
 	trigram := SetEnigmaAndGetScore(plugboard, ciphertext, "trigram", m)
 	ioc := SetEnigmaAndGetScore(plugboard, ciphertext, "ioc", m)
@@ -186,8 +223,8 @@ func HillClimbAttack(ciphertext string, m map[string]float64) (string, float64,
 }
 
 // IterateHillClimbAttack iterates through the HillClimbAttack
-func IterateHillClimbAttack(ciphertext string, dict map[string]float64) (string, string, string) {
-	base := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+func IterateHillClimbAttack(ciphertext string, dict map[string]float64, c *SteckerConstraints) (enigma.Plugboard, string, string) {
+	base := c.Known
 	baseRotor := string("?1 ?2 IV III")
 	basePosition := string("?1 ?2 B Q")
 	rotors := []string{"I", "II", "V", "VI", "Beta", "Gamma"}
@@ -195,7 +232,7 @@ func IterateHillClimbAttack(ciphertext string, dict map[string]float64) (string,
 
 	// Keep Track of the best Plugboard and Score
 	bestScore := math.Inf(-100)
-	bestPlugboard := string("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	bestPlugboard := base
 	bestRotor := ""
 	bestPosition := ""
 
@@ -239,7 +276,7 @@ func IterateHillClimbAttack(ciphertext string, dict map[string]float64) (string,
 						count++
 					}
 
-					plugboard, trigram, _ := HillClimbAttack(ciphertext, dict)
+					plugboard, trigram, _ := HillClimbAttack(ciphertext, dict, c)
 					if trigram > bestScore {
 						bestScore = trigram
 						bestPlugboard = plugboard
@@ -255,38 +292,27 @@ func IterateHillClimbAttack(ciphertext string, dict map[string]float64) (string,
 
 }
 
-// FormatPlugboard formats the Plugboard string into a list of plugboard pairs
-func FormatPlugboard(plugboard string) string {
-
-	formatted := string("")
-	base := string("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
-
-	for i := 0; i < len(plugboard); i++ {
-
-		actual := string(base[i])
-		current := string(plugboard[i])
-
-		if actual != current && !strings.Contains(formatted, actual) && !strings.Contains(formatted, current) {
-			formatted = formatted + actual + current + " "
-		}
+func main() {
+	cables := flag.Int("cables", 0, "exact number of plugboard cables (0 for any up to 13)")
+	known := flag.String("known", "", "known plugboard pairs, e.g. \"AB CD\"")
+	flag.Parse()
 
+	constraints, err := NewSteckerConstraints(*cables, *known)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	return formatted
-}
-
-func main() {
 	// Read File Contents
-	ciphertext := ReadFileContents()
+	ciphertext := ReadFileContents(flag.Arg(0))
 	m := CreateTrigramDictionary()
 
-	bestPlugboard, bestRotor, bestPosition := IterateHillClimbAttack(ciphertext, m)
+	bestPlugboard, bestRotor, bestPosition := IterateHillClimbAttack(ciphertext, m, constraints)
 	CLIDefaults.Rotors = bestRotor
 	CLIDefaults.Position = bestPosition
-	CLIDefaults.Plugboard = bestPlugboard
+	CLIDefaults.Plugboard = bestPlugboard.String()
 
 	fmt.Println(bestRotor)
 	fmt.Println(bestPosition)
-	fmt.Println(FormatPlugboard(bestPlugboard))
+	fmt.Println(CLIDefaults.Plugboard)
 
 }
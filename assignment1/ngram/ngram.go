@@ -0,0 +1,152 @@
+// Package ngram scores how much a text looks like English from trigram
+// frequencies, as in english_trigrams.txt: one trigram of A-Z and its
+// count per line. The hill climbing attack on Enigma uses it on
+// uppercase letters; Fitness extends it to arbitrary bytes for attacks on
+// modern ciphers whose plaintext keeps its case, spaces and punctuation.
+package ngram
+
+import (
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Model holds the log probability of each trigram.
+type Model struct {
+	// Trigrams maps a trigram of A-Z to the log of its share of all the
+	// trigrams counted.
+	Trigrams map[string]float64
+
+	// Floor is the log probability given to a trigram never counted, a
+	// hundredth of one counted once.
+	Floor float64
+}
+
+// Load reads a trigram file.
+func Load(filename string) (*Model, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]float64)
+
+	// Keeps track of the total Frequency
+	total := float64(0)
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, &strconv.NumError{Func: "Load", Num: line, Err: strconv.ErrSyntax}
+		}
+		freq, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		m[fields[0]] = float64(freq)
+		total += float64(freq)
+	}
+
+	// Now we divide each element by the Total Frequency.
+	for k, v := range m {
+		m[k] = math.Log(v / total)
+	}
+	return &Model{Trigrams: m, Floor: math.Log(0.01 / total)}, nil
+}
+
+// Score adds up the log probabilities of the trigrams of text that were
+// counted, skipping the rest, as the Enigma attack always has. Text of
+// the same length scores comparably.
+func (m *Model) Score(text string) float64 {
+	score := float64(0)
+	for i := 0; i+3 <= len(text); i++ {
+		if val, ok := m.Trigrams[text[i:i+3]]; ok {
+			score += val
+		}
+	}
+	return score
+}
+
+// letterFrequency is how often each letter appears in English, in percent.
+var letterFrequency = [26]float64{
+	8.2, 1.5, 2.8, 4.3, 12.7, 2.2, 2.0, 6.1, 7.0, 0.15, 0.77, 4.0, 2.4,
+	6.7, 7.5, 1.9, 0.095, 6.0, 6.3, 9.1, 2.8, 0.98, 2.4, 0.15, 2.0, 0.074,
+}
+
+// Byte classes, as log probabilities of a byte of English prose: mostly
+// lowercase letters and spaces, some capitals, digits and punctuation, and
+// control bytes or anything above ASCII almost never.
+var (
+	spaceScore  = math.Log(0.15)
+	commonScore = math.Log(0.03 / 22)
+	otherScore  = math.Log(0.002 / 80)
+	binaryScore = math.Log(0.00001)
+
+	lowerScore, upperScore, letterLog [26]float64
+)
+
+func init() {
+	for i, f := range letterFrequency {
+		lowerScore[i] = math.Log(0.77 * f / 100)
+		upperScore[i] = math.Log(0.04 * f / 100)
+		letterLog[i] = math.Log(f / 100)
+	}
+}
+
+// ByteScore is the log probability of b appearing in English text.
+func ByteScore(b byte) float64 {
+	switch {
+	case b >= 'a' && b <= 'z':
+		return lowerScore[b-'a']
+	case b >= 'A' && b <= 'Z':
+		return upperScore[b-'A']
+	case b == ' ':
+		return spaceScore
+	case b >= '0' && b <= '9', strings.IndexByte(".,'\"!?-:;()\n", b) >= 0:
+		return commonScore
+	case b >= 0x20 && b < 0x7f, b == '\t', b == '\r':
+		return otherScore
+	}
+	return binaryScore
+}
+
+// Fitness scores arbitrary bytes as English: ByteScore for every byte,
+// plus, for every trigram of letters, case folded, how much likelier it is
+// than its three letters drawn independently. Trigrams never counted get
+// Floor, so unlike Score it can compare candidate texts that differ in a
+// few bytes, and text does not gain by avoiding letters.
+func (m *Model) Fitness(text []byte) float64 {
+	score := float64(0)
+	for i, b := range text {
+		score += ByteScore(b)
+		if i < 2 {
+			continue
+		}
+		tri := []byte{upper(text[i-2]), upper(text[i-1]), upper(b)}
+		if !isUpper(tri[0]) || !isUpper(tri[1]) || !isUpper(tri[2]) {
+			continue
+		}
+		if val, ok := m.Trigrams[string(tri)]; ok {
+			score += val
+		} else {
+			score += m.Floor
+		}
+		for _, c := range tri {
+			score -= letterLog[c-'A']
+		}
+	}
+	return score
+}
+
+func upper(b byte) byte {
+	if b >= 'a' && b <= 'z' {
+		return b - 'a' + 'A'
+	}
+	return b
+}
+
+func isUpper(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
+)
+
+var usage = `
+rotor-recover [flags] <crib file>
+
+Recovers the wiring of an unknown fast (rightmost) rotor from known
+plaintext. Every other part of the machine - the remaining rotors, the
+reflector, the ring settings and the plugboard - must be known.
+
+Each line of the crib file holds the window letters at the start of a
+message (one per rotor, unknown rotor last), a plaintext and its
+ciphertext, e.g.
+
+	AAB WETTERVORHERSAGE QZUEKDHWGTALOYPCNM
+
+The more cribs, the fewer wirings survive; a few hundred letters usually
+pin the rotor down completely. Recovered rotors are printed in the form
+used by presets.go.
+
+Flags:
+`
+
+// Alphabet is the identity wiring, used as a stand-in for the unknown rotor.
+const Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Crib is a known plaintext/ciphertext pair and the start position of the
+// machine that produced it.
+type Crib struct {
+	Start      string
+	Plaintext  string
+	Ciphertext string
+}
+
+// Constraint ties the unknown wiring N at two inputs: N(B) = T(N(A)).
+// T is the rest of the machine seen from the unknown rotor's contacts.
+type Constraint struct {
+	A, B int
+	T    [26]int
+}
+
+// Machine describes the known part of the Enigma.
+type Machine struct {
+	Rotors    []string
+	Rings     []int
+	Reflector string
+	Plugboard enigma.Plugboard
+}
+
+// ReadCribs reads a crib file, one crib per line. Blank lines and lines
+// starting with "#" are skipped.
+func ReadCribs(name string, rotors int) ([]Crib, error) {
+	bytes, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var cribs []Crib
+	for n, line := range strings.Split(string(bytes), "\n") {
+		line = strings.TrimSpace(strings.ToUpper(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected start, plaintext and ciphertext", name, n+1)
+		}
+		crib := Crib{fields[0], fields[1], fields[2]}
+		if len(crib.Start) != rotors {
+			return nil, fmt.Errorf("%s:%d: start %q should have %d letters", name, n+1, crib.Start, rotors)
+		}
+		if len(crib.Plaintext) != len(crib.Ciphertext) {
+			return nil, fmt.Errorf("%s:%d: plaintext and ciphertext lengths differ", name, n+1)
+		}
+		for _, text := range fields {
+			if strings.Trim(text, Alphabet) != "" {
+				return nil, fmt.Errorf("%s:%d: only letters A-Z are allowed", name, n+1)
+			}
+		}
+		cribs = append(cribs, crib)
+	}
+	return cribs, nil
+}
+
+// BuildConstraints runs every crib through the known part of the machine,
+// with an identity rotor carrying the given turnover in place of the
+// unknown one, and collects one constraint per letter.
+func BuildConstraints(machine Machine, cribs []Crib, turnover string) ([]Constraint, error) {
+	var constraints []Constraint
+	for _, crib := range cribs {
+		rotors := make([]*enigma.Rotor, len(machine.Rotors)+1)
+		for i, id := range machine.Rotors {
+			rotors[i] = enigma.HistoricRotors.GetByID(id)
+			if rotors[i] == nil {
+				return nil, fmt.Errorf("unknown rotor %q", id)
+			}
+		}
+		rotors[len(rotors)-1] = enigma.NewRotor(Alphabet, "?", turnover)
+		for i, rotor := range rotors {
+			rotor.Offset = enigma.CharToIndex(crib.Start[i])
+			rotor.Ring = machine.Rings[i] - 1
+		}
+		unknown := rotors[len(rotors)-1]
+
+		e := &enigma.Enigma{Plugboard: *enigma.NewPlugboard(nil), Rotors: rotors}
+		reflector := enigma.HistoricReflectors.GetByID(machine.Reflector)
+		if reflector == nil {
+			return nil, fmt.Errorf("unknown reflector %q", machine.Reflector)
+		}
+		e.Reflector = *reflector
+
+		for k := range crib.Plaintext {
+			e.Step()
+
+			// The unknown rotor maps x to N(x+s)-s
+			s := (unknown.Offset - unknown.Ring + 26) % 26
+
+			c := Constraint{}
+			for y := 0; y < 26; y++ {
+				c.T[y] = (e.Map((y-s+26)%26) + s) % 26
+			}
+			a := machine.Plugboard.Apply(enigma.CharToIndex(crib.Plaintext[k]))
+			b := machine.Plugboard.Apply(enigma.CharToIndex(crib.Ciphertext[k]))
+			c.A = (a + s) % 26
+			c.B = (b + s) % 26
+			constraints = append(constraints, c)
+		}
+	}
+	return constraints, nil
+}
+
+// Solver searches for the wirings consistent with a set of constraints.
+type Solver struct {
+	Constraints []Constraint
+	Max         int
+	Solutions   [][26]int
+
+	byInput [26][]int
+}
+
+// NewSolver indexes the constraints by the inputs they touch.
+func NewSolver(constraints []Constraint, max int) *Solver {
+	s := &Solver{Constraints: constraints, Max: max}
+	for i, c := range constraints {
+		s.byInput[c.A] = append(s.byInput[c.A], i)
+		s.byInput[c.B] = append(s.byInput[c.B], i)
+	}
+	return s
+}
+
+// Solve fills Solutions with up to Max wirings. Unknown entries are -1;
+// they are left open only for inputs that no constraint touches.
+func (s *Solver) Solve() {
+	var wiring [26]int
+	var used [26]bool
+	for i := range wiring {
+		wiring[i] = -1
+	}
+	s.search(wiring, used)
+}
+
+func (s *Solver) search(wiring [26]int, used [26]bool) {
+	if len(s.Solutions) >= s.Max {
+		return
+	}
+
+	// Branch on the constrained input with the most constraints
+	input := -1
+	for x := 0; x < 26; x++ {
+		if wiring[x] == -1 && len(s.byInput[x]) > 0 && (input == -1 || len(s.byInput[x]) > len(s.byInput[input])) {
+			input = x
+		}
+	}
+	if input == -1 {
+		s.Solutions = append(s.Solutions, complete(wiring, used))
+		return
+	}
+
+	for v := 0; v < 26; v++ {
+		if used[v] {
+			continue
+		}
+		nextWiring, nextUsed := wiring, used
+		if s.propagate(&nextWiring, &nextUsed, input, v) {
+			s.search(nextWiring, nextUsed)
+		}
+	}
+}
+
+// propagate assigns N(x) = v and follows the constraints from there,
+// returning false on a contradiction.
+func (s *Solver) propagate(wiring *[26]int, used *[26]bool, x, v int) bool {
+	wiring[x] = v
+	used[v] = true
+	queue := []int{x}
+	for len(queue) > 0 {
+		x, queue = queue[0], queue[1:]
+		for _, i := range s.byInput[x] {
+			c := &s.Constraints[i]
+
+			// T is an involution, so the constraint reads the same both ways
+			y := c.B
+			if x == c.B {
+				y = c.A
+			}
+			v := c.T[wiring[x]]
+			if wiring[y] == v {
+				continue
+			}
+			if wiring[y] != -1 || used[v] {
+				return false
+			}
+			wiring[y] = v
+			used[v] = true
+			queue = append(queue, y)
+		}
+	}
+	return true
+}
+
+// complete fills in the last input if only one is left open.
+func complete(wiring [26]int, used [26]bool) [26]int {
+	open, free := -1, -1
+	for i := 0; i < 26; i++ {
+		if wiring[i] == -1 {
+			if open != -1 {
+				return wiring
+			}
+			open = i
+		}
+		if !used[i] {
+			free = i
+		}
+	}
+	if open != -1 {
+		wiring[open] = free
+	}
+	return wiring
+}
+
+// FormatWiring writes a wiring as a NewRotor mapping string, with "?" for
+// inputs that could not be determined.
+func FormatWiring(wiring [26]int) string {
+	mapping := make([]byte, 26)
+	for i, v := range wiring {
+		if v == -1 {
+			mapping[i] = '?'
+		} else {
+			mapping[i] = enigma.IndexToChar(v)
+		}
+	}
+	return string(mapping)
+}
+
+func main() {
+	rotorsFlag := flag.String("rotors", "I II", "known rotors left to right, without the unknown one")
+	ringsFlag := flag.String("rings", "", "ring settings for all rotors including the unknown one (default all 1)")
+	reflector := flag.String("reflector", "B", "reflector ID")
+	plugs := flag.String("plugboard", "", "plugboard pairs, e.g. \"AB CD\"")
+	turnovers := flag.String("turnover", "", "turnover letter(s) of the unknown rotor (default try each letter)")
+	id := flag.String("id", "X", "ID to give the recovered rotor")
+	max := flag.Int("max", 10, "maximum number of wirings to report per turnover")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	machine := Machine{Rotors: strings.Fields(*rotorsFlag), Reflector: *reflector}
+	for range machine.Rotors {
+		machine.Rings = append(machine.Rings, 1)
+	}
+	machine.Rings = append(machine.Rings, 1)
+	if *ringsFlag != "" {
+		rings := strings.Fields(*ringsFlag)
+		if len(rings) != len(machine.Rings) {
+			log.Fatalf("expected %d ring settings, got %d", len(machine.Rings), len(rings))
+		}
+		for i, ring := range rings {
+			value, err := strconv.Atoi(ring)
+			if err != nil || value < 1 || value > 26 {
+				log.Fatalf("invalid ring setting %q", ring)
+			}
+			machine.Rings[i] = value
+		}
+	}
+	if len(machine.Rotors) < 2 {
+		log.Fatal("at least two known rotors are needed")
+	}
+	plugboard, err := enigma.ParsePlugboard(*plugs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	machine.Plugboard = *plugboard
+
+	cribs, err := ReadCribs(flag.Arg(0), len(machine.Rings))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Candidate turnovers: each one changes when the middle rotor steps
+	candidates := []string{*turnovers}
+	if *turnovers == "" {
+		candidates = strings.Split(Alphabet, "")
+	}
+
+	// Several turnovers may explain the cribs equally well, e.g. when no
+	// message is long enough to reach the notch
+	var order []string
+	found := make(map[string][]string)
+	for _, turnover := range candidates {
+		constraints, err := BuildConstraints(machine, cribs, turnover)
+		if err != nil {
+			log.Fatal(err)
+		}
+		solver := NewSolver(constraints, *max)
+		solver.Solve()
+		for _, wiring := range solver.Solutions {
+			mapping := FormatWiring(wiring)
+			if _, ok := found[mapping]; !ok {
+				order = append(order, mapping)
+			}
+			found[mapping] = append(found[mapping], turnover)
+		}
+	}
+
+	if len(order) == 0 {
+		fmt.Println("no wiring is consistent with the cribs")
+		os.Exit(1)
+	}
+	for _, mapping := range order {
+		turnover := found[mapping]
+		fmt.Printf("*NewRotor(%q, %q, %q),", mapping, *id, turnover[0])
+		if len(turnover) > 1 {
+			fmt.Printf(" // turnover any of %s", strings.Join(turnover, ""))
+		}
+		fmt.Println()
+	}
+}
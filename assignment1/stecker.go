@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
+)
+
+// SteckerConstraints restricts the plugboards visited by the hill climb:
+// how many cables may (and must) be used, and which plugs are already
+// known. Letters plugged in Known are locked and never rewired.
+type SteckerConstraints struct {
+	MinCables int
+	MaxCables int
+	Known     enigma.Plugboard
+}
+
+// NewSteckerConstraints builds the constraints for a search using exactly
+// the given number of cables (0 allows anything up to enigma.MaxCables) and a
+// partially known plugboard in pair notation.
+func NewSteckerConstraints(cables int, known string) (*SteckerConstraints, error) {
+	if cables < 0 || cables > enigma.MaxCables {
+		return nil, fmt.Errorf("cable count %d out of range 0-%d", cables, enigma.MaxCables)
+	}
+	plugboard, err := enigma.ParsePlugboard(known)
+	if err != nil {
+		return nil, err
+	}
+	c := &SteckerConstraints{MinCables: cables, MaxCables: cables, Known: *plugboard}
+	if cables == 0 {
+		c.MaxCables = enigma.MaxCables
+	}
+	if plugboard.Cables() > c.MaxCables {
+		return nil, fmt.Errorf("known plugboard %q already uses more than %d cables", known, c.MaxCables)
+	}
+	return c, nil
+}
+
+// Locked reports whether letter a is fixed by the known plugs.
+func (c *SteckerConstraints) Locked(a int) bool {
+	return c.Known.IsPlugged(a)
+}
+
+// Allows reports whether the plugboard keeps every known plug and stays
+// within MaxCables.
+func (c *SteckerConstraints) Allows(p *enigma.Plugboard) bool {
+	for i := 0; i < 26; i++ {
+		if c.Locked(i) && p[i] != c.Known[i] {
+			return false
+		}
+	}
+	return p.Cables() <= c.MaxCables
+}
+
+// Complete reports whether the plugboard uses at least MinCables cables.
+func (c *SteckerConstraints) Complete(p *enigma.Plugboard) bool {
+	return p.Cables() >= c.MinCables
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
+)
+
+func TestNewSteckerConstraints(t *testing.T) {
+	for _, c := range []struct {
+		cables   int
+		known    string
+		min, max int
+	}{
+		{0, "", 0, enigma.MaxCables},
+		{10, "", 10, 10},
+		{enigma.MaxCables, "", enigma.MaxCables, enigma.MaxCables},
+		{2, "AB CD", 2, 2},
+		{0, "AB CD", 0, enigma.MaxCables},
+	} {
+		s, err := NewSteckerConstraints(c.cables, c.known)
+		if err != nil {
+			t.Errorf("NewSteckerConstraints(%d, %q): %v", c.cables, c.known, err)
+			continue
+		}
+		if s.MinCables != c.min || s.MaxCables != c.max || s.Known.String() != c.known {
+			t.Errorf("NewSteckerConstraints(%d, %q) = %d-%d cables, known %q, want %d-%d, %q",
+				c.cables, c.known, s.MinCables, s.MaxCables, s.Known.String(), c.min, c.max, c.known)
+		}
+	}
+
+	for _, c := range []struct {
+		cables int
+		known  string
+	}{
+		{-1, ""},
+		{enigma.MaxCables + 1, ""},
+		{1, "AB CD"},
+		{0, "AB AC"},
+		{0, "A"},
+	} {
+		if _, err := NewSteckerConstraints(c.cables, c.known); err == nil {
+			t.Errorf("NewSteckerConstraints(%d, %q) succeeded", c.cables, c.known)
+		}
+	}
+}
+
+func TestSteckerConstraintsAllows(t *testing.T) {
+	c, err := NewSteckerConstraints(3, "AB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, letter := range "ABCZ" {
+		a := enigma.CharToIndex(byte(letter))
+		if want := letter == 'A' || letter == 'B'; c.Locked(a) != want {
+			t.Errorf("Locked(%c) = %v, want %v", letter, c.Locked(a), want)
+		}
+	}
+
+	for _, p := range []struct {
+		pairs    string
+		allows   bool
+		complete bool
+	}{
+		{"AB", true, false},
+		{"AB CD", true, false},
+		{"AB CD EF", true, true},
+		{"AB CD EF GH", false, true},
+		{"CD EF GH", false, true},
+		{"AC DE FG", false, true},
+		{"", false, false},
+	} {
+		board, err := enigma.ParsePlugboard(p.pairs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Allows(board); got != p.allows {
+			t.Errorf("Allows(%q) = %v, want %v", p.pairs, got, p.allows)
+		}
+		if got := c.Complete(board); got != p.complete {
+			t.Errorf("Complete(%q) = %v, want %v", p.pairs, got, p.complete)
+		}
+	}
+}
+
+// steckerCiphertext is English enciphered on the default machine with
+// steckerPlugs.
+const steckerPlugs = "AQ EK HR"
+
+func steckerCiphertext(t *testing.T) string {
+	plugboard, err := enigma.ParsePlugboard(steckerPlugs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := SetDefaultsForEnigmaMachine()
+	e.Plugboard = *plugboard
+	return e.EncodeString(strings.Repeat("THEQUICKBROWNFOXJUMPSOVERTHELAZYDOG", 3))
+}
+
+func TestFillPlugboard(t *testing.T) {
+	ciphertext := steckerCiphertext(t)
+	for _, c := range []struct {
+		cables int
+		known  string
+	}{
+		{1, ""},
+		{4, ""},
+		{4, "AQ"},
+		{5, "AQ EK HR"},
+		{2, "AQ EK"},
+	} {
+		s, err := NewSteckerConstraints(c.cables, c.known)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := FillPlugboard(s.Known, ciphertext, "ioc", nil, s)
+		if got.Cables() != c.cables {
+			t.Errorf("%d cables, known %q: filled to %q, %d cables", c.cables, c.known, got.String(), got.Cables())
+		}
+		if !s.Allows(&got) {
+			t.Errorf("%d cables, known %q: filled to %q, which breaks the constraints", c.cables, c.known, got.String())
+		}
+	}
+
+	// Nothing to do once MinCables is reached, and never beyond MaxCables
+	s, err := NewSteckerConstraints(0, "AQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FillPlugboard(s.Known, ciphertext, "ioc", nil, s); got != s.Known {
+		t.Errorf("no minimum: filled %q to %q", s.Known.String(), got.String())
+	}
+}
+
+func TestIteratePlugboardKeepsConstraints(t *testing.T) {
+	ciphertext := steckerCiphertext(t)
+	s, err := NewSteckerConstraints(3, "AQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := FillPlugboard(s.Known, ciphertext, "ioc", nil, s)
+	score := SetEnigmaAndGetScore(start, ciphertext, "ioc", nil)
+	got := IteratePlugboard(score, start, ciphertext, "ioc", nil, s)
+	if !s.Allows(&got) || !s.Complete(&got) {
+		t.Errorf("climbed from %q to %q, which breaks the constraints", start.String(), got.String())
+	}
+}
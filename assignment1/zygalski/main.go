@@ -0,0 +1,346 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
+)
+
+var usage = `
+zygalski <command> [flags] [arguments]
+
+Zygalski's perforated sheets. From late 1938 each message carried a
+ground setting in clear followed by the doubled message key enciphered
+at it, e.g. "RTJ WAHWIK". An indicator whose first and fourth (second and
+fifth, third and sixth) letters agree is a "female"; it can only occur
+at rotor core positions where the product of the two permutations has a
+fixed point, whatever the plugboard. Overlaying the females of a day's
+traffic leaves few rotor orders and ring settings standing.
+
+Commands:
+	sheets [flags]                   print the sheets for a rotor order, holes marked "O"
+	match [flags] <indicator file>   print the rotor orders and ring settings surviving a day's females
+	simulate [flags]                 produce a day's indicators for a known daily key
+
+Run "zygalski <command> -h" for the flags of each command.
+`
+
+// Alphabet is the list of letters in index order.
+const Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Indicator is a message's clear ground setting and its enciphered doubled
+// message key.
+type Indicator struct {
+	Ground string
+	Key    string
+}
+
+// Females returns the pairs (0 for 1-4, 1 for 2-5, 2 for 3-6) whose letters
+// repeat.
+func (ind Indicator) Females() []int {
+	var pairs []int
+	for k := 0; k < 3; k++ {
+		if ind.Key[k] == ind.Key[k+3] {
+			pairs = append(pairs, k)
+		}
+	}
+	return pairs
+}
+
+// Sheets holds the permutation of a rotor order at every core position
+// (window letter minus ring setting), indexed by left*676+middle*26+right.
+type Sheets struct {
+	Order     []string
+	Reflector string
+	perms     [][26]byte
+	steps     map[string][6][3]int
+}
+
+// NewSheets computes the permutations of a rotor order with the given
+// reflector at every core position.
+func NewSheets(order []string, reflector string) (*Sheets, error) {
+	if err := checkIDs(order, reflector); err != nil {
+		return nil, err
+	}
+	config := make([]enigma.RotorConfig, len(order))
+	for i, id := range order {
+		config[i] = enigma.RotorConfig{ID: id, Start: 'A', Ring: 1}
+	}
+	e := enigma.NewEnigma(config, reflector, *enigma.NewPlugboard(nil))
+
+	s := &Sheets{Order: order, Reflector: reflector, perms: make([][26]byte, 26*26*26), steps: make(map[string][6][3]int)}
+	for core := range s.perms {
+		e.Rotors[0].Offset = core / 676
+		e.Rotors[1].Offset = core / 26 % 26
+		e.Rotors[2].Offset = core % 26
+		for i, v := range e.Permutation() {
+			s.perms[core][i] = byte(v)
+		}
+	}
+	return s, nil
+}
+
+// checkIDs returns an error for a rotor or reflector that is not one of
+// the historic ones.
+func checkIDs(order []string, reflector string) error {
+	for _, id := range order {
+		if enigma.HistoricRotors.GetByID(id) == nil {
+			return fmt.Errorf("unknown rotor %q", id)
+		}
+	}
+	if enigma.HistoricReflectors.GetByID(reflector) == nil {
+		return fmt.Errorf("unknown reflector %q", reflector)
+	}
+	return nil
+}
+
+// Windows returns the window letters at each of the six key presses of an
+// indicator typed from the given ground setting. Stepping depends only on
+// the windows, not on the ring settings.
+func (s *Sheets) Windows(ground string) [6][3]int {
+	if windows, ok := s.steps[ground]; ok {
+		return windows
+	}
+	config := make([]enigma.RotorConfig, len(s.Order))
+	for i, id := range s.Order {
+		config[i] = enigma.RotorConfig{ID: id, Start: ground[i], Ring: 1}
+	}
+	e := enigma.NewEnigma(config, s.Reflector, *enigma.NewPlugboard(nil))
+
+	var windows [6][3]int
+	for k := range windows {
+		e.Step()
+		for i := range windows[k] {
+			windows[k][i] = e.Rotors[i].Offset
+		}
+	}
+	s.steps[ground] = windows
+	return windows
+}
+
+// Female reports whether the product of the permutations at presses k and
+// k+3 has a fixed point, i.e. whether a female can occur in pair k.
+func (s *Sheets) Female(windows [6][3]int, rings [3]int, k int) bool {
+	first := &s.perms[core(windows[k], rings)]
+	second := &s.perms[core(windows[k+3], rings)]
+	for x := 0; x < 26; x++ {
+		if second[first[x]] == byte(x) {
+			return true
+		}
+	}
+	return false
+}
+
+func core(window [3]int, rings [3]int) int {
+	return (window[0]-rings[0]+26)%26*676 + (window[1]-rings[1]+26)%26*26 + (window[2]-rings[2]+26)%26
+}
+
+// Match returns every ring setting (0-based) under which all the females
+// can occur.
+func (s *Sheets) Match(indicators []Indicator) [][3]int {
+	var survivors [][3]int
+	for ring := 0; ring < 26*26*26; ring++ {
+		rings := [3]int{ring / 676, ring / 26 % 26, ring % 26}
+		ok := true
+		for _, ind := range indicators {
+			windows := s.Windows(ind.Ground)
+			for _, k := range ind.Females() {
+				if !s.Female(windows, rings, k) {
+					ok = false
+					break
+				}
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			survivors = append(survivors, rings)
+		}
+	}
+	return survivors
+}
+
+// ReadIndicators reads "GROUND KEYKEY" pairs, one per line.
+func ReadIndicators(name string) ([]Indicator, error) {
+	bytes, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var indicators []Indicator
+	for n, line := range strings.Split(strings.ToUpper(string(bytes)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 || len(fields[0]) != 3 || len(fields[1]) != 6 ||
+			strings.Trim(fields[0]+fields[1], Alphabet) != "" {
+			return nil, fmt.Errorf("%s:%d: expected a ground setting and a six letter indicator", name, n+1)
+		}
+		indicators = append(indicators, Indicator{fields[0], fields[1]})
+	}
+	return indicators, nil
+}
+
+func formatRings(rings [3]int) string {
+	return fmt.Sprintf("%d %d %d", rings[0]+1, rings[1]+1, rings[2]+1)
+}
+
+func sheets() {
+	flags := flag.NewFlagSet("sheets", flag.ExitOnError)
+	order := flags.String("order", "I II III", "rotor order")
+	reflector := flags.String("reflector", "B", "reflector ID")
+	left := flags.String("left", "", "only print the sheet for this left rotor letter")
+	flags.Parse(os.Args[2:])
+
+	rotors := strings.Fields(*order)
+	if len(rotors) != 3 {
+		log.Fatal("expected 3 rotors")
+	}
+	s, err := NewSheets(rotors, *reflector)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Classic sheets: ring settings at A, holes for females in pair 1-4
+	for l := 0; l < 26; l++ {
+		if *left != "" && strings.ToUpper(*left)[0] != Alphabet[l] {
+			continue
+		}
+		fmt.Printf("%s sheet %c\n   %s\n", strings.Join(rotors, " "), Alphabet[l], Alphabet)
+		for m := 0; m < 26; m++ {
+			row := make([]byte, 26)
+			for r := 0; r < 26; r++ {
+				windows := s.Windows(string([]byte{Alphabet[l], Alphabet[m], Alphabet[r]}))
+				row[r] = '.'
+				if s.Female(windows, [3]int{}, 0) {
+					row[r] = 'O'
+				}
+			}
+			fmt.Printf("%c  %s\n", Alphabet[m], row)
+		}
+		fmt.Println()
+	}
+}
+
+func match() {
+	flags := flag.NewFlagSet("match", flag.ExitOnError)
+	pool := flags.String("rotors", "I II III IV V", "rotors to choose the rotor order from")
+	reflector := flags.String("reflector", "B", "reflector ID")
+	max := flags.Int("max", 20, "maximum number of ring settings to print per rotor order")
+	flags.Parse(os.Args[2:])
+	if flags.NArg() != 1 {
+		log.Fatal("usage: zygalski match [flags] <indicator file>")
+	}
+
+	indicators, err := ReadIndicators(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var females []Indicator
+	count := 0
+	for _, ind := range indicators {
+		if n := len(ind.Females()); n > 0 {
+			females = append(females, ind)
+			count += n
+		}
+	}
+	fmt.Printf("%d females in %d indicators\n", count, len(indicators))
+	if count == 0 {
+		os.Exit(1)
+	}
+
+	found := 0
+	for _, order := range enigma.RotorOrders(strings.Fields(*pool)) {
+		s, err := NewSheets(order, *reflector)
+		if err != nil {
+			log.Fatal(err)
+		}
+		survivors := s.Match(females)
+		for i, rings := range survivors {
+			if i == *max {
+				fmt.Printf("rotors %s ... %d more\n", strings.Join(order, " "), len(survivors)-i)
+				break
+			}
+			fmt.Printf("rotors %s rings %s\n", strings.Join(order, " "), formatRings(rings))
+		}
+		found += len(survivors)
+	}
+	if found == 0 {
+		fmt.Println("no rotor order survives")
+		os.Exit(1)
+	}
+}
+
+func simulate() {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	order := flags.String("order", "I II III", "rotor order")
+	ringsFlag := flags.String("rings", "1 1 1", "ring settings")
+	reflector := flags.String("reflector", "B", "reflector ID")
+	plugs := flags.String("plugboard", "", "plugboard pairs, e.g. \"AB CD\"")
+	count := flags.Int("n", 100, "number of indicators")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "random seed for the ground settings and message keys")
+	flags.Parse(os.Args[2:])
+
+	rotors := strings.Fields(*order)
+	rings := strings.Fields(*ringsFlag)
+	if len(rotors) != 3 || len(rings) != 3 {
+		log.Fatal("expected 3 rotors and 3 ring settings")
+	}
+	if err := checkIDs(rotors, *reflector); err != nil {
+		log.Fatal(err)
+	}
+	plugboard, err := enigma.ParsePlugboard(*plugs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	letters := func() string {
+		s := make([]byte, 3)
+		for i := range s {
+			s[i] = Alphabet[rng.Intn(26)]
+		}
+		return string(s)
+	}
+	for i := 0; i < *count; i++ {
+		ground, key := letters(), letters()
+		config := make([]enigma.RotorConfig, 3)
+		for j, id := range rotors {
+			ring, err := strconv.Atoi(rings[j])
+			if err != nil || ring < 1 || ring > 26 {
+				log.Fatalf("invalid ring setting %q", rings[j])
+			}
+			config[j] = enigma.RotorConfig{ID: id, Start: ground[j], Ring: ring}
+		}
+		e := enigma.NewEnigma(config, *reflector, *plugboard)
+		fmt.Println(ground, e.EncodeString(key+key))
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "sheets":
+		sheets()
+	case "match":
+		match()
+	case "simulate":
+		simulate()
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,245 @@
+package checksum
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/paddingoracle"
+)
+
+// IVSize is the length of encrypt-auth-chk's IV, its initial counter block.
+const IVSize = 16
+
+// ErrShort is returned for a ciphertext without room for the IV and the
+// checksum.
+var ErrShort = errors.New("checksum: ciphertext too short")
+
+// ErrTooLarge is returned by Decrypt for a checksum too long to brute
+// force through the oracle.
+var ErrTooLarge = errors.New("checksum: checksum too long to recover through the oracle")
+
+// ErrNoGuess is returned when the oracle accepts no value for a byte.
+var ErrNoGuess = errors.New("checksum: the oracle accepted no guess")
+
+// ErrKeyed is returned by Decrypt and Flip for a ciphertext under a MAC.
+var ErrKeyed = errors.New("checksum: ciphertext is authenticated with a MAC")
+
+// ErrResisted is returned by Forge when the oracle accepts no forgery.
+var ErrResisted = errors.New("checksum: the oracle accepted no forgery")
+
+// ErrRejected is returned by Forge when the oracle rejects the untouched
+// ciphertext, so its answers mean nothing.
+var ErrRejected = errors.New("checksum: the oracle rejects the original ciphertext")
+
+// maxBruteForce is the largest checksum, in bytes, whose keystream Decrypt
+// and Flip will search for: 65536 queries.
+const maxBruteForce = 2
+
+// guessOrder tries printable ASCII and whitespace before the rest.
+var guessOrder = func() []byte {
+	order := []byte("\n\r\t")
+	for b := 0x20; b < 0x7f; b++ {
+		order = append(order, byte(b))
+	}
+	for b := 0; b < 256; b++ {
+		if (b < 0x20 || b >= 0x7f) && b != '\n' && b != '\r' && b != '\t' {
+			order = append(order, byte(b))
+		}
+	}
+	return order
+}()
+
+// query asks o about IV || C.
+func query(o paddingoracle.Oracle, IV []byte, C []byte) (bool, error) {
+	return o.Query(append(append([]byte{}, IV...), C...))
+}
+
+// unwrap strips C's header, if it has one, and returns an oracle that puts
+// it back in front of every query. The header must name m.
+func unwrap(o paddingoracle.Oracle, m Model, C []byte) (paddingoracle.Oracle, []byte, error) {
+	i, rest, ok := SplitHeader(C)
+	if !ok {
+		return o, C, nil
+	}
+	if i.Keyed() {
+		return nil, nil, ErrKeyed
+	}
+	if i.Name != m.Name() {
+		return nil, nil, fmt.Errorf("checksum: ciphertext uses %s, not %s", i.Name, m.Name())
+	}
+	if o != nil {
+		o = paddingoracle.Wrap(o, C[0:HeaderSize], nil)
+	}
+	return o, rest, nil
+}
+
+// bruteForce tries every value of a Size byte checksum field, XORed into
+// field, followed by rest, and returns the XOR the oracle accepted.
+func bruteForce(o paddingoracle.Oracle, m Model, IV []byte, field []byte, rest []byte) ([]byte, error) {
+	if m.Size() > maxBruteForce {
+		return nil, ErrTooLarge
+	}
+	x := make([]byte, m.Size())
+	for v := 0; v < 1<<(8*uint(m.Size())); v++ {
+		for i := range x {
+			x[i] = byte(v >> (8 * uint(len(x)-1-i)))
+		}
+		C := make([]byte, 0, len(field)+len(rest))
+		for i := range field {
+			C = append(C, field[i]^x[i])
+		}
+		ok, err := query(o, IV, append(C, rest...))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return x, nil
+		}
+	}
+	return nil, ErrNoGuess
+}
+
+// Decrypt recovers the message in an encrypt-auth-chk ciphertext
+// C = IV || E(Sum(M) || M), with or without a header in front, through an
+// oracle that reports whether a ciphertext's checksum is valid, for any
+// model whose checksum of a message tells apart every value of its last
+// byte, as all of these do.
+//
+// With an empty message the checksum field must decrypt to Sum(""), so
+// trying every value of the field gives its keystream, 256^Size queries
+// at most. After that, with the keystream known up to byte i, the message
+// is cut to zeros(i) || M[i] by sending the keystream itself for the first
+// i bytes, and the field is set to the checksum of zeros(i) || g for each
+// guess g until the oracle accepts one.
+func Decrypt(o paddingoracle.Oracle, m Model, C []byte) ([]byte, error) {
+	o, C, err := unwrap(o, m, C)
+	if err != nil {
+		return nil, err
+	}
+	if len(C) < IVSize+m.Size() {
+		return nil, ErrShort
+	}
+	IV, C := C[0:IVSize], C[IVSize:]
+	n := len(C) - m.Size()
+
+	// Keystream under the checksum field
+	kSum, err := bruteForce(o, m, IV, m.Sum(nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	M := make([]byte, 0, n)
+	keystream := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		found := false
+		probe := make([]byte, i+1)
+		for _, g := range guessOrder {
+			probe[i] = g
+			sum := m.Sum(probe)
+			for j := range sum {
+				sum[j] ^= kSum[j]
+			}
+			ok, err := query(o, IV, append(append(sum, keystream...), C[m.Size()+i]))
+			if err != nil {
+				return M, err
+			}
+			if ok {
+				M = append(M, g)
+				keystream = append(keystream, C[m.Size()+i]^g)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return M, fmt.Errorf("byte %d: %v", i, ErrNoGuess)
+		}
+	}
+	return M, nil
+}
+
+// Flip rewrites the known plaintext old at offset off of the message in
+// C = IV || E(Sum(M) || M), with or without a header, into new, which must be the same length, and
+// fixes up the encrypted checksum to match. CTR lets the bytes be changed
+// by XORing old ⊕ new into the ciphertext. The checksum fix comes from
+// the model when it can be worked out without the rest of the message;
+// otherwise, if o is not nil, each value of the fix is tried against the
+// oracle, 256^Size queries at most.
+func Flip(o paddingoracle.Oracle, m Model, C []byte, off int, old []byte, new []byte) ([]byte, error) {
+	if len(old) != len(new) {
+		return nil, errors.New("checksum: old and new text differ in length")
+	}
+	header := C
+	o, C, err := unwrap(o, m, C)
+	if err != nil {
+		return nil, err
+	}
+	header = header[:len(header)-len(C)]
+	if len(C) < IVSize+m.Size() {
+		return nil, ErrShort
+	}
+	n := len(C) - IVSize - m.Size()
+	if off < 0 || off+len(old) > n {
+		return nil, fmt.Errorf("checksum: %d bytes at offset %d do not fit a %d byte message", len(old), off, n)
+	}
+
+	C = append([]byte{}, C...)
+	body := C[IVSize+m.Size():]
+	for i := range old {
+		body[off+i] ^= old[i] ^ new[i]
+	}
+
+	fix, ok := m.Fixup(n, off, old, new)
+	if !ok {
+		if o == nil {
+			return nil, fmt.Errorf("checksum: fixing up %s here needs the oracle", m.Name())
+		}
+		fix, err = bruteForce(o, m, C[0:IVSize], C[IVSize:IVSize+m.Size()], body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range fix {
+		C[IVSize+i] ^= fix[i]
+	}
+	return append(append([]byte{}, header...), C...), nil
+}
+
+// Forge makes the first move of the attacks on a ciphertext under a MAC,
+// header || IV || E(M) || T, to show that it goes nowhere: it flips the
+// last bit of the message and tries every value of the tag's last byte,
+// as the checksum attacks would, and returns the forgery the oracle
+// accepted, if any, and the queries made. Without the key, a MAC of the
+// flipped message matches in all of its bytes only by chance.
+func Forge(o paddingoracle.Oracle, C []byte) ([]byte, int, error) {
+	i, rest, ok := SplitHeader(C)
+	if !ok || !i.Keyed() {
+		return nil, 0, errors.New("checksum: ciphertext has no MAC header")
+	}
+	if len(rest) < IVSize+1+i.Size {
+		return nil, 0, ErrShort
+	}
+
+	ok, err := o.Query(C)
+	if err != nil || !ok {
+		if err == nil {
+			err = ErrRejected
+		}
+		return nil, 1, err
+	}
+	queries := 1
+
+	forged := append([]byte{}, C...)
+	forged[len(forged)-i.Size-1] ^= 1
+	for v := 0; v < 256; v++ {
+		forged[len(forged)-1] = C[len(C)-1] ^ byte(v)
+		ok, err := o.Query(forged)
+		queries++
+		if err != nil {
+			return nil, queries, err
+		}
+		if ok {
+			return forged, queries, nil
+		}
+	}
+	return nil, queries, ErrResisted
+}
@@ -0,0 +1,207 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/paddingoracle"
+)
+
+var (
+	testKey = []byte("sixteen byte key")
+	testMAC = []byte("and a MAC key...")
+	testIV  = []byte("counter block 16")
+)
+
+const testMessage = "Attack at dawn!"
+
+// ctr is encrypt-auth-chk's AES-CTR under testKey.
+func ctr(IV []byte, text []byte) []byte {
+	block, err := aes.NewCipher(testKey)
+	if err != nil {
+		panic(err)
+	}
+	out := make([]byte, len(text))
+	cipher.NewCTR(block, IV).XORKeyStream(out, text)
+	return out
+}
+
+// seal encrypts M as encrypt-auth-chk does under i: header || IV ||
+// E(Sum(M) || M) for a checksum, header || IV || E(M) || HMAC for
+// hmac-sha256. A nil i leaves out the header, as the original did with
+// the byte sum.
+func seal(i *Integrity, M []byte) []byte {
+	if i == nil {
+		return append(append([]byte{}, testIV...), ctr(testIV, append(Sum8{}.Sum(M), M...))...)
+	}
+	C := append(Header(*i), testIV...)
+	if !i.Keyed() {
+		return append(C, ctr(testIV, append(i.Model.Sum(M), M...))...)
+	}
+	C = append(C, ctr(testIV, M)...)
+	mac := hmac.New(sha256.New, testMAC)
+	mac.Write(C)
+	return mac.Sum(C)
+}
+
+// oracle accepts a ciphertext that would decrypt: its checksum or its tag
+// is right.
+func oracle(t *testing.T) paddingoracle.OracleFunc {
+	return func(C []byte) (bool, error) {
+		i, rest, ok := SplitHeader(C)
+		if !ok {
+			i, _ = ParseIntegrity("sum")
+		}
+		if len(rest) < IVSize+i.Size {
+			return false, nil
+		}
+		if i.Keyed() {
+			if i.Name != "hmac-sha256" {
+				t.Fatalf("oracle only knows hmac-sha256, not %s", i.Name)
+			}
+			mac := hmac.New(sha256.New, testMAC)
+			mac.Write(C[:len(C)-i.Size])
+			return hmac.Equal(mac.Sum(nil), C[len(C)-i.Size:]), nil
+		}
+		P := ctr(rest[:IVSize], rest[IVSize:])
+		return bytes.Equal(i.Model.Sum(P[i.Size:]), P[:i.Size]), nil
+	}
+}
+
+// open decrypts a checksummed ciphertext from seal and checks it.
+func open(t *testing.T, C []byte) []byte {
+	if ok, _ := oracle(t)(C); !ok {
+		t.Fatalf("%x does not decrypt", C)
+	}
+	i, rest, ok := SplitHeader(C)
+	if !ok {
+		i, _ = ParseIntegrity("sum")
+	}
+	return ctr(rest[:IVSize], rest[IVSize:])[i.Size:]
+}
+
+func TestDecrypt(t *testing.T) {
+	for _, i := range Integrities {
+		if i.Keyed() {
+			continue
+		}
+		i := i
+		C := seal(&i, []byte(testMessage))
+		M, err := Decrypt(oracle(t), i.Model, C)
+		if i.Size > maxBruteForce {
+			if err != ErrTooLarge {
+				t.Errorf("%s: %q, %v, want %v", i.Name, M, err, ErrTooLarge)
+			}
+			continue
+		}
+		if err != nil || string(M) != testMessage {
+			t.Errorf("%s: recovered %q, %v, want %q", i.Name, M, err, testMessage)
+		}
+	}
+
+	// The original format has no header
+	if M, err := Decrypt(oracle(t), Sum8{}, seal(nil, []byte(testMessage))); err != nil || string(M) != testMessage {
+		t.Errorf("no header: recovered %q, %v, want %q", M, err, testMessage)
+	}
+	if _, err := Decrypt(oracle(t), Sum8{}, testIV[:IVSize-1]); err != ErrShort {
+		t.Errorf("short ciphertext: %v, want %v", err, ErrShort)
+	}
+	xor, _ := ParseIntegrity("xor")
+	if _, err := Decrypt(oracle(t), Sum8{}, seal(&xor, []byte(testMessage))); err == nil || !strings.Contains(err.Error(), "uses xor, not sum") {
+		t.Errorf("xor ciphertext attacked as sum: %v", err)
+	}
+}
+
+func TestFlip(t *testing.T) {
+	want := strings.Replace(testMessage, "dawn", "dusk", 1)
+	type target struct {
+		m Model
+		C []byte
+	}
+	targets := []target{{Sum8{}, seal(nil, []byte(testMessage))}}
+	for _, i := range Integrities {
+		if !i.Keyed() {
+			i := i
+			targets = append(targets, target{i.Model, seal(&i, []byte(testMessage))})
+		}
+	}
+	for _, target := range targets {
+		m, C := target.m, target.C
+
+		// "dawn" and "dusk" have different byte sums, so sum needs the
+		// oracle; the others fix up from the model alone
+		_, known := m.Fixup(len(testMessage), 10, []byte("dawn"), []byte("dusk"))
+		var o paddingoracle.Oracle
+		if !known {
+			o = oracle(t)
+			if _, err := Flip(nil, m, C, 10, []byte("dawn"), []byte("dusk")); err == nil {
+				t.Errorf("%s: flipped without the oracle", m.Name())
+			}
+		}
+		F, err := Flip(o, m, C, 10, []byte("dawn"), []byte("dusk"))
+		if err != nil {
+			t.Errorf("%s: %v", m.Name(), err)
+			continue
+		}
+		header := len(C) - IVSize - m.Size() - len(testMessage)
+		if len(F) != len(C) || !bytes.Equal(F[:header], C[:header]) {
+			t.Errorf("%s: header changed from %x to %x", m.Name(), C, F)
+		}
+		if got := open(t, F); string(got) != want {
+			t.Errorf("%s: flipped to %q, want %q", m.Name(), got, want)
+		}
+	}
+
+	for _, c := range []struct {
+		off      int
+		old, new string
+	}{{-1, "A", "B"}, {12, "dawn", "dusk"}, {0, "Attack", "Defend!"}} {
+		if _, err := Flip(nil, XOR8{}, seal(nil, []byte(testMessage)), c.off, []byte(c.old), []byte(c.new)); err == nil {
+			t.Errorf("Flip of %q to %q at %d succeeded", c.old, c.new, c.off)
+		}
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	hmacSHA256, _ := ParseIntegrity("hmac-sha256")
+	C := seal(&hmacSHA256, []byte(testMessage))
+	if _, err := Decrypt(oracle(t), Sum8{}, C); err != ErrKeyed {
+		t.Errorf("Decrypt: %v, want %v", err, ErrKeyed)
+	}
+	if _, err := Flip(oracle(t), XOR8{}, C, 0, []byte("A"), []byte("B")); err != ErrKeyed {
+		t.Errorf("Flip: %v, want %v", err, ErrKeyed)
+	}
+
+	forged, queries, err := Forge(oracle(t), C)
+	if err != ErrResisted || forged != nil || queries != 257 {
+		t.Errorf("Forge = %x, %d queries, %v, want %d queries, %v", forged, queries, err, 257, ErrResisted)
+	}
+
+	// An oracle that rejects the genuine ciphertext says nothing
+	reject := paddingoracle.OracleFunc(func([]byte) (bool, error) { return false, nil })
+	if _, queries, err := Forge(reject, C); err != ErrRejected || queries != 1 {
+		t.Errorf("rejecting oracle: %d queries, %v, want 1, %v", queries, err, ErrRejected)
+	}
+	broken := errors.New("oracle broken")
+	fail := paddingoracle.OracleFunc(func([]byte) (bool, error) { return false, broken })
+	if _, _, err := Forge(fail, C); err != broken {
+		t.Errorf("failing oracle: %v, want %v", err, broken)
+	}
+
+	// Forge only takes a MAC, and an oracle that accepts anything is fooled
+	sum, _ := ParseIntegrity("sum")
+	if _, _, err := Forge(oracle(t), seal(&sum, []byte(testMessage))); err == nil {
+		t.Error("Forge of a checksummed ciphertext succeeded")
+	}
+	accept := paddingoracle.OracleFunc(func([]byte) (bool, error) { return true, nil })
+	forged, queries, err = Forge(accept, C)
+	if err != nil || queries != 2 || bytes.Equal(forged, C) {
+		t.Errorf("accepting oracle: %x, %d queries, %v, want a forgery in 2", forged, queries, err)
+	}
+}
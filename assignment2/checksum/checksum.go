@@ -0,0 +1,134 @@
+// Package checksum holds the unkeyed integrity checks encrypt-auth-chk can
+// put in front of a message before encrypting it with AES-CTR: the
+// original byte sum mod 256, an XOR of all bytes and CRC-32. None of them
+// resists an attacker, who can recompute or fix up any of them, which is
+// what decrypt-attack-chk and flip-chk demonstrate. It also describes
+// encrypt-auth-chk's header, which can name a MAC instead, so that the
+// same attacks can show a MAC resisting them.
+package checksum
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// ErrModel is returned by Parse for an unknown checksum.
+var ErrModel = errors.New("checksum: unknown checksum")
+
+// Model is an unkeyed checksum over a whole message.
+type Model interface {
+	// Name is the name Parse accepts.
+	Name() string
+
+	// Size is the length of a checksum in bytes.
+	Size() int
+
+	// Sum returns the checksum of M.
+	Sum(M []byte) []byte
+
+	// Fixup returns what to XOR into the checksum of an n byte message
+	// when the bytes at off change from old to new, without knowing the
+	// rest of the message. ok is false when that depends on the rest.
+	Fixup(n int, off int, old []byte, new []byte) (fix []byte, ok bool)
+}
+
+// Models lists every model, the default first.
+var Models = []Model{Sum8{}, XOR8{}, CRC32{}}
+
+// Parse returns the model called name.
+func Parse(name string) (Model, error) {
+	for _, m := range Models {
+		if m.Name() == name {
+			return m, nil
+		}
+	}
+	return nil, ErrModel
+}
+
+// Sum8 is the sum of the bytes mod 256, encrypt-auth-chk's original check.
+type Sum8 struct{}
+
+// Name returns "sum".
+func (Sum8) Name() string { return "sum" }
+
+// Size returns 1.
+func (Sum8) Size() int { return 1 }
+
+// Sum adds up the bytes of M mod 256.
+func (Sum8) Sum(M []byte) []byte {
+	var sum byte
+	for _, b := range M {
+		sum += b
+	}
+	return []byte{sum}
+}
+
+// Fixup only knows the answer when the edit leaves the sum alone: the
+// checksum goes from S to S+d, and S ⊕ (S+d) depends on S.
+func (s Sum8) Fixup(n int, off int, old []byte, new []byte) ([]byte, bool) {
+	if s.Sum(old)[0] != s.Sum(new)[0] {
+		return nil, false
+	}
+	return []byte{0}, true
+}
+
+// XOR8 is the XOR of the bytes.
+type XOR8 struct{}
+
+// Name returns "xor".
+func (XOR8) Name() string { return "xor" }
+
+// Size returns 1.
+func (XOR8) Size() int { return 1 }
+
+// Sum XORs together the bytes of M.
+func (XOR8) Sum(M []byte) []byte {
+	var sum byte
+	for _, b := range M {
+		sum ^= b
+	}
+	return []byte{sum}
+}
+
+// Fixup is the XOR of the changed bits: the checksum is linear.
+func (x XOR8) Fixup(n int, off int, old []byte, new []byte) ([]byte, bool) {
+	return linearFixup(x, n, off, old, new), true
+}
+
+// CRC32 is the IEEE CRC-32, big-endian.
+type CRC32 struct{}
+
+// Name returns "crc32".
+func (CRC32) Name() string { return "crc32" }
+
+// Size returns 4.
+func (CRC32) Size() int { return 4 }
+
+// Sum returns the CRC-32 of M.
+func (CRC32) Sum(M []byte) []byte {
+	sum := make([]byte, 4)
+	binary.BigEndian.PutUint32(sum, crc32.ChecksumIEEE(M))
+	return sum
+}
+
+// Fixup uses the CRC being affine: for messages of the same length,
+// CRC(A ⊕ B) = CRC(A) ⊕ CRC(B) ⊕ CRC(0…0).
+func (c CRC32) Fixup(n int, off int, old []byte, new []byte) ([]byte, bool) {
+	return linearFixup(c, n, off, old, new), true
+}
+
+// linearFixup is Fixup for a checksum affine over GF(2): the change to the
+// checksum is that of the change to the message, less that of zeros.
+func linearFixup(m Model, n int, off int, old []byte, new []byte) []byte {
+	delta := make([]byte, n)
+	for i := range old {
+		delta[off+i] = old[i] ^ new[i]
+	}
+	fix := m.Sum(delta)
+	zero := m.Sum(make([]byte, n))
+	for i := range fix {
+		fix[i] ^= zero[i]
+	}
+	return fix
+}
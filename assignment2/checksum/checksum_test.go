@@ -0,0 +1,118 @@
+package checksum
+
+import (
+	"bytes"
+	mrand "math/rand"
+	"testing"
+)
+
+const testRounds = 200
+
+func TestParse(t *testing.T) {
+	for _, m := range Models {
+		if got, err := Parse(m.Name()); err != nil || got != m {
+			t.Errorf("Parse(%q) = %v, %v", m.Name(), got, err)
+		}
+		if got := len(m.Sum([]byte("abc"))); got != m.Size() {
+			t.Errorf("%s: %d byte checksum, want %d", m.Name(), got, m.Size())
+		}
+	}
+	if _, err := Parse("md5"); err != ErrModel {
+		t.Errorf("Parse(md5): %v, want %v", err, ErrModel)
+	}
+}
+
+func TestSum(t *testing.T) {
+	for _, c := range []struct {
+		m    Model
+		M    string
+		want []byte
+	}{
+		{Sum8{}, "", []byte{0}},
+		{Sum8{}, "\xff\x02", []byte{1}},
+		{XOR8{}, "", []byte{0}},
+		{XOR8{}, "\x0f\xf0\x01", []byte{0xfe}},
+		{CRC32{}, "", []byte{0, 0, 0, 0}},
+		{CRC32{}, "123456789", []byte{0xcb, 0xf4, 0x39, 0x26}},
+	} {
+		if got := c.m.Sum([]byte(c.M)); !bytes.Equal(got, c.want) {
+			t.Errorf("%s of %q = %x, want %x", c.m.Name(), c.M, got, c.want)
+		}
+	}
+}
+
+// TestFixup edits random messages and checks that, where a model says it
+// knows the fix, XORing it in gives the checksum of the edited message.
+func TestFixup(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	for _, m := range Models {
+		known := 0
+		for r := 0; r < testRounds; r++ {
+			M := make([]byte, 1+rng.Intn(40))
+			rng.Read(M)
+			off := rng.Intn(len(M))
+			new := make([]byte, rng.Intn(len(M)-off+1))
+			rng.Read(new)
+
+			// Every fourth edit keeps the byte sum, which Sum8 can fix
+			if r%4 == 0 && len(new) == 2 {
+				new[1] = M[off] + M[off+1] - new[0]
+			}
+			old := append([]byte{}, M[off:off+len(new)]...)
+
+			fix, ok := m.Fixup(len(M), off, old, new)
+			if !ok {
+				continue
+			}
+			known++
+			want := m.Sum(M)
+			copy(M[off:], new)
+			got := m.Sum(M)
+			for i := range fix {
+				got[i] ^= fix[i]
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: %x at %d to %x in %d bytes: fixed to %x, want %x", m.Name(), old, off, new, len(M), got, want)
+			}
+		}
+		if known == 0 {
+			t.Errorf("%s: no edit could be fixed up", m.Name())
+		}
+	}
+
+	// Sum8 cannot fix an edit that changes the sum
+	if _, ok := (Sum8{}).Fixup(3, 1, []byte("a"), []byte("b")); ok {
+		t.Error("sum: fixed up a change to the sum")
+	}
+	for _, m := range []Model{XOR8{}, CRC32{}} {
+		if _, ok := m.Fixup(3, 1, []byte("a"), []byte("b")); !ok {
+			t.Errorf("%s: could not fix up an edit", m.Name())
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	for _, i := range Integrities {
+		got, err := ParseIntegrity(i.Name)
+		if err != nil || got != i {
+			t.Errorf("ParseIntegrity(%q) = %v, %v", i.Name, got, err)
+		}
+		if i.Keyed() != (i.Model == nil) || !i.Keyed() && i.Model.Size() != i.Size {
+			t.Errorf("%s: keyed %v, size %d", i.Name, i.Keyed(), i.Size)
+		}
+		C := append(Header(i), "rest"...)
+		got, rest, ok := SplitHeader(C)
+		if !ok || got != i || string(rest) != "rest" {
+			t.Errorf("SplitHeader(%x) = %v, %q, %v", C, got, rest, ok)
+		}
+	}
+	if _, err := ParseIntegrity("md5"); err != ErrIntegrity {
+		t.Errorf("ParseIntegrity(md5): %v, want %v", err, ErrIntegrity)
+	}
+
+	for _, C := range []string{"", "EC\x01", "EC\x02\x01rest", "CE\x01\x01rest", "EC\x01\x07rest"} {
+		if _, rest, ok := SplitHeader([]byte(C)); ok || string(rest) != C {
+			t.Errorf("SplitHeader(%q) = %q, %v, want no header", C, rest, ok)
+		}
+	}
+}
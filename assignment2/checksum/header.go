@@ -0,0 +1,84 @@
+package checksum
+
+import (
+	"bytes"
+	"errors"
+)
+
+// encrypt-auth-chk ciphertexts start with a four byte header: the magic
+// "EC", a version and the ID of the integrity check that follows. Those
+// from before the header, IV || E(Sum(M) || M) under the byte sum, have
+// none, and a random IV only looks like a header once in 2^32.
+//
+// Under a checksum the rest is IV || E(Sum(M) || M), as before. Under a
+// MAC it is IV || E(M) || T, with T = MAC(kMac, header || IV || E(M)), so
+// a modified ciphertext is rejected before it is decrypted.
+var headerMagic = []byte("EC")
+
+const headerVersion = 1
+
+// HeaderSize is the length of the header.
+const HeaderSize = 4
+
+// ErrIntegrity is returned by ParseIntegrity for an unknown check.
+var ErrIntegrity = errors.New("checksum: unknown integrity check")
+
+// Integrity is one of the checks encrypt-auth-chk can protect a message
+// with.
+type Integrity struct {
+	// ID is the header's last byte.
+	ID byte
+
+	// Name is what the -integrity flags accept.
+	Name string
+
+	// Size is the length of the checksum or tag in bytes.
+	Size int
+
+	// Model is the checksum, or nil for a keyed MAC.
+	Model Model
+}
+
+// Keyed reports whether the check is a MAC.
+func (i Integrity) Keyed() bool {
+	return i.Model == nil
+}
+
+// Integrities lists every check, the default first.
+var Integrities = []Integrity{
+	{1, "sum", 1, Sum8{}},
+	{2, "xor", 1, XOR8{}},
+	{3, "crc32", 4, CRC32{}},
+	{4, "hmac-sha256", 32, nil},
+	{5, "poly1305", 16, nil},
+	{6, "cmac", 16, nil},
+}
+
+// ParseIntegrity returns the check called name.
+func ParseIntegrity(name string) (Integrity, error) {
+	for _, i := range Integrities {
+		if i.Name == name {
+			return i, nil
+		}
+	}
+	return Integrity{}, ErrIntegrity
+}
+
+// Header returns the header for i.
+func Header(i Integrity) []byte {
+	return append(append([]byte{}, headerMagic...), headerVersion, i.ID)
+}
+
+// SplitHeader returns the check named by C's header and the rest of C, or
+// ok false and C itself if C has no valid header.
+func SplitHeader(C []byte) (i Integrity, rest []byte, ok bool) {
+	if len(C) < HeaderSize || !bytes.Equal(C[0:2], headerMagic) || C[2] != headerVersion {
+		return Integrity{}, C, false
+	}
+	for _, i := range Integrities {
+		if i.ID == C[3] {
+			return i, C[HeaderSize:], true
+		}
+	}
+	return Integrity{}, C, false
+}
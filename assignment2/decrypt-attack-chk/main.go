@@ -4,10 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"os/exec"
-)
+	"log"
 
-var filePtr *string
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/paddingoracle"
+)
 
 func check(e error) {
 	if e != nil {
@@ -15,67 +16,74 @@ func check(e error) {
 	}
 }
 
-func callOracle(IV []byte, C []byte) bool {
+func main() {
 
-	result := append(IV, C...)
-	ioutil.WriteFile("test.txt", result, 0666)
+	filePtr := flag.String("i", "", "input file")
+	integrityPtr := flag.String("integrity", "", "integrity check the target uses: sum, xor, crc32, hmac-sha256, poly1305 or cmac; by default the one in the ciphertext's header, or sum without one")
+	oraclePtr := flag.String("oracle", "command", "oracle: command runs ./decrypt-test-chk, http posts to -url")
+	urlPtr := flag.String("url", "http://localhost:8081/decrypt", "endpoint for the http oracle")
+	invalidPtr := flag.Int("invalid", 0, "HTTP status meaning an invalid checksum or MAC, by default 400 for checksums and 403 for MACs")
+	ratePtr := flag.Float64("rate", 0, "requests per second for the http oracle, 0 for no limit")
+	flag.Parse()
 
-	output, _ := exec.Command("./decrypt-test-chk", "-i=test.txt").Output()
+	C, err := ioutil.ReadFile(*filePtr)
+	check(err)
 
-	if string(output) == "INVALID CHECKSUM" {
-		return false
+	integrity, _, ok := checksum.SplitHeader(C)
+	if *integrityPtr != "" || !ok {
+		name := *integrityPtr
+		if name == "" {
+			name = "sum"
+		}
+		integrity, err = checksum.ParseIntegrity(name)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-	return true
-}
-
-func decryptCiphertext(block []byte, IV []byte) []byte {
 
-	// length is the length of the plaintext
-	length := len(block)
-
-	// Create plaintext
-	M := make([]byte, 0)
-
-	// initial value of inter is only the checksum
-	inter := make([]byte, 1)
-	for j := 0; j < 255; j++ {
-		inter[0] = byte(j)
-		success := callOracle(IV, inter)
-		if success {
-			break
+	// The padding oracle's interface fits a checksum oracle just as well
+	invalid, status := "INVALID CHECKSUM", 400
+	if integrity.Keyed() {
+		invalid, status = "INVALID MAC", 403
+	}
+	if *invalidPtr != 0 {
+		status = *invalidPtr
+	}
+	var oracle paddingoracle.Oracle
+	switch *oraclePtr {
+	case "command":
+		oracle = &paddingoracle.CommandOracle{
+			Name:    "./decrypt-test-chk",
+			Args:    []string{"-i={}", "-integrity=" + integrity.Name},
+			Invalid: invalid,
 		}
+	case "http":
+		oracle = &paddingoracle.HTTPOracle{URL: *urlPtr, Invalid: status, Rate: *ratePtr}
+	default:
+		log.Fatalf("unknown oracle %q", *oraclePtr)
 	}
-	// We iterate through the length of the plaintext
-	for i := 1; i < length; i++ {
 
-		// temp is a guess for the next byte
-		temp := make([]byte, 1)
-		for j := 0; j <= 255; j++ {
-			temp[0] = byte(j)
-			success := callOracle(IV, append(inter, temp...))
-			if success {
-				plaintext := block[i] ^ byte(j)
-				M = append(M, plaintext)
-				inter = append(inter, temp...)
-				break
-			}
+	// A MAC leaves the attack nothing to work with, which is the point
+	if integrity.Keyed() {
+		forged, queries, err := checksum.Forge(oracle, C)
+		if err == checksum.ErrResisted {
+			fmt.Printf("%s resists the forgery: the target rejected all %d modified ciphertexts\n", integrity.Name, queries-1)
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
 		}
+		fmt.Printf("%s FORGED after %d queries: %x\n", integrity.Name, queries, forged)
+		return
 	}
-	return M
-}
-
-func main() {
-
-	filePtr = flag.String("i", "", "input file")
-	flag.Parse()
 
-	C, err := ioutil.ReadFile(*filePtr)
-	check(err)
-
-	IV := make([]byte, 16)
-	copy(IV, C[0:16])
-	C = C[16:]
-
-	fmt.Println(string(decryptCiphertext(C, IV)))
+	M, err := checksum.Decrypt(oracle, integrity.Model, C)
+	if err != nil {
+		if len(M) > 0 {
+			fmt.Printf("recovered %d bytes before the failure:\n%q\n", len(M), M)
+		}
+		log.Fatal(err)
+	}
+	fmt.Println(string(M))
 
 }
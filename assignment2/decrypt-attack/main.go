@@ -1,13 +1,52 @@
 package main
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
 	"os/exec"
+	"time"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/paddingoracle"
 )
 
-var filePtr *string
+var usage = `
+decrypt-attack [decrypt] [flags] -i <ciphertext file>
+decrypt-attack encrypt [flags] -i <plaintext file> -o <ciphertext file>
+
+decrypt decrypts an encrypt-auth ciphertext through a padding oracle.
+encrypt forges a ciphertext for any plaintext through the same oracle
+(CBC-R), then hands it to the target to show whether it is accepted:
+in mte mode the padding is right but the tag is not, so encrypt-auth
+still answers INVALID MAC. Oracles:
+
+	command   run ./decrypt-test on every guess (default)
+	http      POST every guess to -url; status -invalid means bad padding
+	local     decrypt in-process with encrypt-auth's code and -key, the
+	          fastest way to watch the attack work
+	timing    time the in-process decrypt instead of reading its answer:
+	          mte only computes the MAC after valid padding (Lucky
+	          Thirteen), so a target that reports every failure alike
+	          still leaks. Each guess goes behind -prefix bytes of
+	          random blocks to make the MAC take measurably long
+	timing-command
+	          time ./encrypt-auth decrypt the same way; process start-up
+	          is noisy, so it needs a far longer -prefix
+
+The timing oracles only decrypt, one query at a time, and calibrate
+against the last two blocks of the ciphertext first to find how many
+-samples each query needs. On a noisy machine that can be hundreds, and
+the attack takes a long time.
+
+Flags:
+`
 
 func check(e error) {
 	if e != nil {
@@ -15,77 +54,246 @@ func check(e error) {
 	}
 }
 
-func callOracle(C []byte) bool {
-
-	ioutil.WriteFile("test.txt", C, 0666)
+// newOracle builds the oracle selected on the command line.
+func newOracle(kind string, mode string, hardened bool, url string, invalid int, rate float64, hexKey string, samples int) (paddingoracle.Oracle, error) {
+	switch kind {
+	case "timing-command":
+		args := []string{"-mode=" + mode, fmt.Sprintf("-hardened=%t", hardened), "decrypt", hexKey, "{}", os.DevNull}
+		return &paddingoracle.TimingOracle{Target: paddingoracle.TimeCommand("./encrypt-auth", args...), Samples: samples}, nil
+	case "timing":
+		key, err := hex.DecodeString(hexKey)
+		if err != nil {
+			return nil, err
+		}
+		m, err := encryptauth.ParseMode(mode)
+		if err != nil {
+			return nil, err
+		}
+		return &paddingoracle.TimingOracle{Target: paddingoracle.TimeFunc(func(C []byte) {
+			if hardened {
+				encryptauth.DecryptHardened(key, C)
+			} else {
+				encryptauth.Open(m, key, nil, C)
+			}
+		}), Samples: samples}, nil
+	case "command":
+		return &paddingoracle.CommandOracle{
+			Name:    "./decrypt-test",
+			Args:    []string{"-i={}", "-mode=" + mode, fmt.Sprintf("-hardened=%t", hardened)},
+			Invalid: "INVALID PADDING",
+		}, nil
+	case "http":
+		return &paddingoracle.HTTPOracle{URL: url, Invalid: invalid, Rate: rate}, nil
+	case "local":
+		key, err := hex.DecodeString(hexKey)
+		if err != nil {
+			return nil, err
+		}
+		m, err := encryptauth.ParseMode(mode)
+		if err != nil {
+			return nil, err
+		}
+		return paddingoracle.OracleFunc(func(C []byte) (bool, error) {
+			var err error
+			if hardened {
+				_, err = encryptauth.DecryptHardened(key, C)
+			} else {
+				_, err = encryptauth.Open(m, key, nil, C)
+			}
+			return err != encryptauth.ErrInvalidPadding, nil
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown oracle %q", kind)
+}
 
-	output, _ := exec.Command("./decrypt-test", "-i=test.txt").Output()
+// verdict submits a forged ciphertext to the target once more and
+// describes the answer: decrypt-test's output, the HTTP status or the
+// in-process error.
+func verdict(kind string, mode string, hardened bool, url string, hexKey string, C []byte) (string, error) {
+	switch kind {
+	case "command":
+		f, err := ioutil.TempFile("", "decrypt-attack")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(C)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", err
+		}
+		output, err := exec.Command("./decrypt-test", "-i="+f.Name(), "-mode="+mode, fmt.Sprintf("-hardened=%t", hardened)).Output()
+		if err != nil {
+			return "", err
+		}
+		return string(output), nil
+	case "http":
+		resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(C))
+		if err != nil {
+			return "", err
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.Status, nil
+	case "local":
+		key, err := hex.DecodeString(hexKey)
+		if err != nil {
+			return "", err
+		}
+		m, err := encryptauth.ParseMode(mode)
+		if err != nil {
+			return "", err
+		}
+		if hardened {
+			_, err = encryptauth.DecryptHardened(key, C)
+		} else {
+			_, err = encryptauth.Open(m, key, nil, C)
+		}
+		if err != nil {
+			return err.Error(), nil
+		}
+		return "SUCCESS", nil
+	}
+	return "", fmt.Errorf("unknown oracle %q", kind)
+}
 
-	if string(output) == "INVALID PADDING" {
-		return false
+// report prints the queries and time each block took to stderr, out of
+// the way of the recovered plaintext.
+func report(attack *paddingoracle.Attack, elapsed time.Duration) {
+	for _, s := range attack.Stats {
+		fmt.Fprintf(os.Stderr, "block %d: %d queries in %v\n", s.Block, s.Queries, s.Time.Round(time.Millisecond))
 	}
-	return true
+	fmt.Fprintf(os.Stderr, "%d queries in %v\n", attack.Queries(), elapsed.Round(time.Millisecond))
 }
 
-func decryptCipherBlock(C []byte, CPrev []byte) []byte {
+func main() {
 
-	// Intermediate block
-	inter := make([]byte, 16)
+	command := "decrypt"
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "decrypt" || args[0] == "encrypt") {
+		command, args = args[0], args[1:]
+	}
 
-	// Guess for the previous block
-	guess := make([]byte, 16)
+	flags := flag.NewFlagSet("decrypt-attack "+command, flag.ExitOnError)
+	filePtr := flags.String("i", "", "input file")
+	outPtr := flags.String("o", "", "output file for the forged ciphertext (encrypt only)")
+	modePtr := flags.String("mode", "mte", "encrypt-auth construction, mte or etm")
+	hardenedPtr := flags.Bool("hardened", false, "attack encrypt-auth's constant time decrypt path")
+	oraclePtr := flags.String("oracle", "command", "oracle: command, http, local, timing or timing-command")
+	urlPtr := flags.String("url", "http://localhost:8080/decrypt", "endpoint for the http oracle")
+	invalidPtr := flags.Int("invalid", 400, "HTTP status meaning invalid padding")
+	ratePtr := flags.Float64("rate", 0, "requests per second for the http oracle, 0 for no limit")
+	keyPtr := flags.String("key", "2b7e151628aed2a6abf7158809cf4f3cf4673bc171a61ed4e877a3976b044458", "hex key for the local oracle")
+	workersPtr := flags.Int("workers", 8, "oracle queries in flight at once")
+	macPtr := flags.Int("mac", 32, "tag length: inside the plaintext for mte, after the ciphertext for etm")
+	samplesPtr := flags.Int("samples", 0, "timings per query for the timing oracles, 0 to choose from the calibration")
+	prefixPtr := flags.Int("prefix", 64*1024, "bytes of random blocks in front of every timing query")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *filePtr == "" || (command == "encrypt" && *outPtr == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
 
-	// Current byte
-	curr := byte(1)
+	oracle, err := newOracle(*oraclePtr, *modePtr, *hardenedPtr, *urlPtr, *invalidPtr, *ratePtr, *keyPtr, *samplesPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Loop from the last byte to the first byte
-	for i := 15; i >= 0; i-- {
-		for j := 0; j <= 255; j++ {
-			guess[i] = byte(j)
+	text, err := ioutil.ReadFile(*filePtr)
+	check(err)
 
-			// Call the Oracle
-			success := callOracle(append(guess, C...))
-			if success {
-				inter[i] = curr ^ byte(j)
-				curr++
-				for k := 15; k >= i; k-- {
-					guess[k] = curr ^ inter[k]
-				}
-				break
-			}
+	attack := &paddingoracle.Attack{Workers: *workersPtr}
+	start := time.Now()
+	defer func() { report(attack, time.Since(start)) }()
+
+	if timing, ok := oracle.(*paddingoracle.TimingOracle); ok {
+		if command != "decrypt" || *modePtr != "mte" {
+			log.Fatal("the timing oracles only decrypt, and only mte: etm checks the MAC before the padding")
+		}
+		if len(text) < 32 || len(text)%16 != 0 {
+			log.Fatal(paddingoracle.ErrLength)
 		}
-	}
 
-	for i := 0; i < 16; i++ {
-		inter[i] = inter[i] ^ CPrev[i]
+		// Concurrent queries would only add noise to each other's timings
+		attack.Workers = 1
+		prefix := make([]byte, *prefixPtr/16*16)
+		_, err := rand.Read(prefix)
+		check(err)
+		err = timing.Calibrate(append(append([]byte{}, prefix...), text[len(text)-32:]...))
+		fmt.Fprintf(os.Stderr, "valid padding takes %v longer than invalid\n", timing.Gap)
+		if timing.Needed > 0 {
+			fmt.Fprintf(os.Stderr, "%d samples per query needed\n", timing.Needed)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		oracle = paddingoracle.Wrap(timing, prefix, nil)
 	}
-	return inter
-}
 
-func decryptCiphertext(C []byte) []byte {
+	if command == "encrypt" {
 
-	// prev is IV initially
-	prev := C[0:16]
+		// In etm mode every guess needs a header and a tag. The attacker
+		// has no valid tag, so encrypt-auth rejects each one on the MAC
+		// before it looks at the padding and the oracle learns nothing
+		var header, tag []byte
+		if *modePtr == "etm" {
+			header = []byte{'E', 'A', 1, 2}
+			tag = make([]byte, *macPtr)
+			oracle = paddingoracle.Wrap(oracle, header, tag)
+		}
 
-	plaintext := make([]byte, 0)
-	for i := 16; i < len(C); i += 16 {
+		attack.Oracle = oracle
+		C, err := attack.Encrypt(text)
+		if err != nil {
+			log.Fatal(err)
+		}
+		C = append(append(header, C...), tag...)
+		check(ioutil.WriteFile(*outPtr, C, 0644))
+		fmt.Printf("forged %d byte ciphertext for %d byte plaintext in %s\n", len(C), len(text), *outPtr)
 
-		temp := decryptCipherBlock(C[i:i+16], prev)
-		prev = C[i : i+16]
-		plaintext = append(plaintext, temp...)
+		answer, err := verdict(*oraclePtr, *modePtr, *hardenedPtr, *urlPtr, *keyPtr, C)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("target says:", answer)
+		return
 	}
-	lastByte := int(plaintext[len(plaintext)-1])
-	return plaintext[0 : len(plaintext)-lastByte-32]
-}
 
-func main() {
-
-	filePtr = flag.String("i", "", "input file")
-	flag.Parse()
-
-	text, err := ioutil.ReadFile(*filePtr)
-	check(err)
+	// In etm mode the attack works on IV||C′ and sends every guess wrapped
+	// in the original header and tag, as an attacker would have to.
+	// In mte mode the plaintext ends with the tag, then the padding
+	macLength := *macPtr
+	if *modePtr == "etm" {
+		if len(text) < 4+32+macLength {
+			fmt.Println("ciphertext too short for etm")
+			return
+		}
+		oracle = paddingoracle.Wrap(oracle, text[0:4], text[len(text)-macLength:])
+		text = text[4 : len(text)-macLength]
+		macLength = 0
+	}
 
-	fmt.Println(string(decryptCiphertext(text)))
+	attack.Oracle = oracle
+	plaintext, err := attack.Decrypt(text)
+	if err != nil {
+		if len(plaintext) > 0 {
+			fmt.Printf("recovered %d bytes before the failure:\n%q\n", len(plaintext), plaintext)
+		}
+		report(attack, time.Since(start))
+		log.Fatal(err)
+	}
 
+	M, err := paddingoracle.Unpad(plaintext, macLength)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Printf("%q\n", plaintext)
+		return
+	}
+	fmt.Println(string(M))
 }
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
 	"os/exec"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/oracleserver"
 )
 
 func check(e error) {
@@ -12,21 +19,98 @@ func check(e error) {
 	}
 }
 
+var errInvalidChecksum = errors.New("INVALID CHECKSUM")
+
+var errInvalidMAC = errors.New("INVALID MAC")
+
+var errInvalidHeader = errors.New("INVALID HEADER")
+
+var errMalformed = errors.New("MALFORMED CIPHERTEXT")
+
+// decrypt runs encrypt-auth-chk on the ciphertext in inputFile.
+func decrypt(integrity string, key string, inputFile string, outputFile string) error {
+	output, err := exec.Command("./encrypt-auth-chk", "-integrity="+integrity, "decrypt", key, inputFile, outputFile).Output()
+	if _, ok := err.(*exec.ExitError); ok {
+		return errMalformed
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range []error{errInvalidChecksum, errInvalidMAC, errInvalidHeader, errMalformed} {
+		if string(output) == e.Error() {
+			return e
+		}
+	}
+	return nil
+}
+
+// status maps decrypt's errors to HTTP statuses for -serve: 400 for a
+// bad checksum or header, 403 for a bad MAC as decrypt-test does, 422 for
+// a ciphertext too short to parse.
+func status(err error) int {
+	switch err {
+	case errInvalidChecksum, errInvalidHeader:
+		return http.StatusBadRequest
+	case errInvalidMAC:
+		return http.StatusForbidden
+	case errMalformed:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// serve decrypts POSTs to /decrypt with encrypt-auth-chk until it fails.
+func serve(addr string, integrity string, key string, safe bool, rate float64, burst int) {
+	server := &oracleserver.Server{
+		Decrypt: func(C []byte) error {
+			f, err := ioutil.TempFile("", "decrypt-test-chk")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(f.Name())
+			_, err = f.Write(C)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			return decrypt(integrity, key, f.Name(), os.DevNull)
+		},
+		Status: status,
+		Safe:   safe,
+		Rate:   rate,
+		Burst:  burst,
+		Log:    log.New(os.Stderr, "", log.LstdFlags),
+	}
+	http.Handle("/decrypt", server)
+	log.Printf("serving %s decryption on http://%s/decrypt", integrity, addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
 func main() {
 
 	var filePtr = flag.String("i", "", "input file")
+	var integrityPtr = flag.String("integrity", "sum", "encrypt-auth-chk integrity check: sum, xor, crc32, hmac-sha256, poly1305 or cmac")
+	var servePtr = flag.String("serve", "", "serve POST /decrypt on this address, e.g. localhost:8081, instead of reading -i")
+	var safePtr = flag.Bool("safe", false, "with -serve, answer every failure with the same status and body")
+	var ratePtr = flag.Float64("rate", 0, "with -serve, requests per second allowed per client, 0 for no limit")
+	var burstPtr = flag.Int("burst", 10, "with -serve, requests a client may make at once")
 	flag.Parse()
 
 	key := "2b7e151628aed2a6abf7158809cf4f3c"
+	if *servePtr != "" {
+		serve(*servePtr, *integrityPtr, key, *safePtr, *ratePtr, *burstPtr)
+		return
+	}
 	inputFile := *filePtr
 	outputFile := "output.txt"
 
-	output, error := exec.Command("./encrypt-auth-chk", "decrypt", key, inputFile, outputFile).Output()
-	check(error)
-
-	if string(output) == "INVALID CHECKSUM" {
-		fmt.Print("INVALID CHECKSUM")
+	err := decrypt(*integrityPtr, key, inputFile, outputFile)
+	if err == errInvalidChecksum || err == errInvalidMAC || err == errInvalidHeader || err == errMalformed {
+		fmt.Print(err)
 	} else {
+		check(err)
 		fmt.Print("SUCCESS")
 	}
 
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
 	"os/exec"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/oracleserver"
 )
 
 func check(e error) {
@@ -12,24 +19,92 @@ func check(e error) {
 	}
 }
 
+// status maps encrypt-auth's errors to HTTP statuses for -serve: 400 for
+// bad padding, 403 for a bad MAC, 400 too for the hardened path's one
+// error and 422 for anything malformed.
+func status(err error) int {
+	switch err {
+	case encryptauth.ErrInvalidPadding:
+		return http.StatusBadRequest
+	case encryptauth.ErrInvalidMAC:
+		return http.StatusForbidden
+	case encryptauth.ErrDecryption:
+		return http.StatusBadRequest
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// serve decrypts POSTs to /decrypt in-process with key until it fails.
+func serve(addr string, key string, mode string, hardened bool, safe bool, rate float64, burst int) {
+	kEnc, err := hex.DecodeString(key)
+	check(err)
+	m, err := encryptauth.ParseMode(mode)
+	check(err)
+
+	server := &oracleserver.Server{
+		Decrypt: func(C []byte) error {
+			if hardened {
+				_, err := encryptauth.DecryptHardened(kEnc, C)
+				return err
+			}
+			_, err := encryptauth.Open(m, kEnc, nil, C)
+			return err
+		},
+		Status: status,
+		Safe:   safe,
+		Rate:   rate,
+		Burst:  burst,
+		Log:    log.New(os.Stderr, "", log.LstdFlags),
+	}
+	http.Handle("/decrypt", server)
+	log.Printf("serving %s decryption on http://%s/decrypt", mode, addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
 func main() {
 
 	var filePtr = flag.String("i", "", "input file")
+	var modePtr = flag.String("mode", "mte", "encrypt-auth construction, mte or etm")
+	var hardenedPtr = flag.Bool("hardened", false, "use encrypt-auth's constant time decrypt path")
+	var servePtr = flag.String("serve", "", "serve POST /decrypt on this address, e.g. localhost:8080, instead of reading -i")
+	var keyPtr = flag.String("key", "2b7e151628aed2a6abf7158809cf4f3cf4673bc171a61ed4e877a3976b044458", "hex key")
+	var safePtr = flag.Bool("safe", false, "with -serve, answer every failure with the same status and body")
+	var ratePtr = flag.Float64("rate", 0, "with -serve, requests per second allowed per client, 0 for no limit")
+	var burstPtr = flag.Int("burst", 10, "with -serve, requests a client may make at once")
 	flag.Parse()
 
-	key := "2b7e151628aed2a6abf7158809cf4f3cf4673bc171a61ed4e877a3976b044458"
+	key := *keyPtr
+	if *servePtr != "" {
+		serve(*servePtr, key, *modePtr, *hardenedPtr, *safePtr, *ratePtr, *burstPtr)
+		return
+	}
 	inputFile := *filePtr
 	outputFile := "output.txt"
 
-	output, error := exec.Command("./encrypt-auth", "decrypt", key, inputFile, outputFile).Output()
-	check(error)
+	// encrypt-auth reports what went wrong in its exit status
+	args := []string{"-mode=" + *modePtr, "decrypt", key, inputFile, outputFile}
+	if *hardenedPtr {
+		args = append([]string{"-hardened"}, args...)
+	}
+	err := exec.Command("./encrypt-auth", args...).Run()
+	status := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		status = exitErr.ExitCode()
+	} else {
+		check(err)
+	}
 
-	if string(output) == "INVALID PADDING" {
+	switch status {
+	case 0:
+		fmt.Print("SUCCESS")
+	case 5:
 		fmt.Print("INVALID PADDING")
-	} else if string(output) == "INVALID MAC" {
+	case 6:
 		fmt.Print("INVALID MAC")
-	} else {
-		fmt.Print("SUCCESS")
+	case 8:
+		fmt.Print("DECRYPTION FAILED")
+	default:
+		fmt.Print("ERROR")
 	}
 
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+)
+
+// ctr is counter mode over any block cipher. The whole 16 byte counter
+// block is incremented as one 128-bit big-endian number, as NIST SP
+// 800-38A and crypto/cipher.NewCTR do, so the keystream only repeats after
+// 2^128 blocks. It implements cipher.Stream: a message may be processed in
+// pieces of any size and gets the same result as in one go.
+type ctr struct {
+	block     cipher.Block
+	counter   [16]byte
+	keystream [16]byte
+	used      int
+}
+
+// newCTR starts the keystream at the counter block iv, which it copies.
+func newCTR(block cipher.Block, iv []byte) cipher.Stream {
+	if len(iv) != block.BlockSize() || len(iv) != 16 {
+		panic("encrypt-auth-chk: counter block must be 16 bytes")
+	}
+	c := &ctr{block: block, used: 16}
+	copy(c.counter[:], iv)
+	return c
+}
+
+// XORKeyStream XORs src with the next len(src) bytes of keystream.
+func (c *ctr) XORKeyStream(dst []byte, src []byte) {
+	if len(dst) < len(src) {
+		panic("encrypt-auth-chk: output smaller than input")
+	}
+	for i := range src {
+		if c.used == len(c.keystream) {
+			c.block.Encrypt(c.keystream[:], c.counter[:])
+			incrementCounter(c.counter[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.keystream[c.used]
+		c.used++
+	}
+}
+
+// incrementCounter adds one to a big-endian counter, wrapping at the top.
+func incrementCounter(counter []byte) {
+	for i := len(counter) - 1; i >= 0; i-- {
+		counter[i]++
+		if counter[i] != 0 {
+			return
+		}
+	}
+}
+
+// twoTo128 is the number of distinct counter blocks.
+var twoTo128 = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// counterRange is the counter blocks a ciphertext's keystream used: length
+// blocks from first, modulo 2^128.
+type counterRange struct {
+	name   string
+	first  *big.Int
+	length *big.Int
+}
+
+// newCounterRange describes a ciphertext [header ||] IV || C′ in name. A
+// MAC tag is counted as if encrypted, which can only report more reuse.
+func newCounterRange(name string, C []byte) (counterRange, error) {
+	_, C, _ = checksum.SplitHeader(C)
+	if len(C) < 16 {
+		return counterRange{}, fmt.Errorf("%s: shorter than the IV", name)
+	}
+	blocks := (len(C) - 16 + 15) / 16
+	return counterRange{name, new(big.Int).SetBytes(C[0:16]), big.NewInt(int64(blocks))}, nil
+}
+
+// overlap reports whether a and b share any counter block and so encrypt
+// part of their plaintexts with the same keystream, and if so the first
+// shared block's offset into a.
+func overlap(a counterRange, b counterRange) (bool, *big.Int) {
+	d := new(big.Int).Sub(b.first, a.first)
+	d.Mod(d, twoTo128)
+	if d.Cmp(a.length) < 0 && b.length.Sign() > 0 {
+		return true, d
+	}
+	d.Sub(a.first, b.first)
+	d.Mod(d, twoTo128)
+	if d.Cmp(b.length) < 0 && a.length.Sign() > 0 {
+		return true, big.NewInt(0)
+	}
+	return false, nil
+}
+
+// detectReuse reads ciphertexts made under one key and prints every pair
+// whose counter ranges overlap. Anyone holding both can XOR them over the
+// overlap and cancel the keystream, as with a two-time pad. It reports
+// whether any did.
+func detectReuse(files []string) bool {
+	ranges := make([]counterRange, 0, len(files))
+	for _, name := range files {
+		C, err := ioutil.ReadFile(name)
+		check(err)
+		r, err := newCounterRange(name, C)
+		check(err)
+		ranges = append(ranges, r)
+	}
+
+	reused := false
+	for i := 0; i < len(ranges); i++ {
+		for j := i + 1; j < len(ranges); j++ {
+			if ok, offset := overlap(ranges[i], ranges[j]); ok {
+				fmt.Printf("%s and %s reuse keystream from block %v of %s\n", ranges[i].name, ranges[j].name, offset, ranges[i].name)
+				reused = true
+			}
+		}
+	}
+	return reused
+}
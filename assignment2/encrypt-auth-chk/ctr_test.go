@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+)
+
+// NIST SP 800-38A, appendix F.5: CTR examples. All of them encrypt the
+// same four blocks from the same initial counter block, whose last byte
+// wraps from ff to 00 after the first block.
+const (
+	nistCounter   = "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"
+	nistPlaintext = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+)
+
+var nistCTRVectors = []struct {
+	name       string
+	key        string
+	ciphertext string
+}{
+	{"SP 800-38A F.5.1 CTR-AES128", "2b7e151628aed2a6abf7158809cf4f3c",
+		"874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff" +
+			"5ae4df3edbd5d35e5b4f09020db03eab1e031dda2fbe03d1792170a0f3009cee"},
+	{"SP 800-38A F.5.3 CTR-AES192", "8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"1abc932417521ca24f2b0459fe7e6e0b090339ec0aa6faefd5ccc2c6f4ce8e94" +
+			"1e36b26bd1ebc670d1bd1d665620abf74f78a7f6d29809585a97daec58c6b050"},
+	{"SP 800-38A F.5.5 CTR-AES256", "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"601ec313775789a5b7a7f504bbf3d228f443e3ca4d62b59aca84e990cacaf5c5" +
+			"2b0930daa23de94ce87017ba2d84988ddfc9c58db67aada613c2dd08457941a6"},
+}
+
+// testRounds is the number of random messages compared with
+// crypto/cipher. They come from a fixed seed, so a failure can be
+// reproduced.
+const testRounds = 200
+
+// carryCounters are initial counter blocks whose increments carry across
+// bytes, up to the wrap of the whole 128 bits back to zero.
+var carryCounters = []string{
+	"000102030405060708090a0b0c0d0eff",
+	"0001020304050607ffffffffffffff00",
+	"fffffffffffffffffffffffffffffff0",
+}
+
+func TestCTRVectors(t *testing.T) {
+	counter, _ := hex.DecodeString(nistCounter)
+	M, _ := hex.DecodeString(nistPlaintext)
+	for _, v := range nistCTRVectors {
+		key, _ := hex.DecodeString(v.key)
+		C, _ := hex.DecodeString(v.ciphertext)
+		if err := compareBlocks(aesCTREncryptNonce(key, M, counter), C); err != nil {
+			t.Errorf("%s encrypt: %v", v.name, err)
+		}
+		if err := compareBlocks(aesCTRDecrypt(key, C, counter), M); err != nil {
+			t.Errorf("%s decrypt: %v", v.name, err)
+		}
+	}
+	if hex.EncodeToString(counter) != nistCounter {
+		t.Errorf("decrypt changed the counter to %x", counter)
+	}
+}
+
+// TestCTRCarry compares the keystream with crypto/cipher.NewCTR from
+// counters whose increments carry across bytes.
+func TestCTRCarry(t *testing.T) {
+	key := make([]byte, 16)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := make([]byte, 300*16)
+	for _, c := range carryCounters {
+		counter, _ := hex.DecodeString(c)
+		want := make([]byte, len(long))
+		cipher.NewCTR(block, counter).XORKeyStream(want, long)
+		if err := compareBlocks(aesCTREncryptNonce(key, long, counter), want); err != nil {
+			t.Errorf("counter %s: %v", c, err)
+		}
+	}
+}
+
+// TestCTRReference compares random keys, counters and lengths with
+// crypto/cipher.NewCTR, whole and fed to one stream in random pieces.
+func TestCTRReference(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	for i := 0; i < testRounds; i++ {
+		key := make([]byte, 16+8*rng.Intn(3))
+		counter := make([]byte, 16)
+		M := make([]byte, rng.Intn(2000))
+		rng.Read(key)
+		rng.Read(counter)
+		rng.Read(M)
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]byte, len(M))
+		cipher.NewCTR(block, counter).XORKeyStream(want, M)
+		if err := compareBlocks(aesCTREncryptNonce(key, M, counter), want); err != nil {
+			t.Fatalf("%d byte message from counter %x: %v", len(M), counter, err)
+		}
+
+		got := make([]byte, len(M))
+		stream := newCTR(block, counter)
+		for start := 0; start < len(M); {
+			end := start + rng.Intn(40)
+			if end > len(M) {
+				end = len(M)
+			}
+			stream.XORKeyStream(got[start:end], M[start:end])
+			start = end
+		}
+		if err := compareBlocks(got, want); err != nil {
+			t.Fatalf("%d byte message in pieces: %v", len(M), err)
+		}
+	}
+}
+
+// TestOverlap checks overlap on counter ranges that do and do not share
+// blocks, including across the wrap at 2^128.
+func TestOverlap(t *testing.T) {
+	r := func(first string, length int64) counterRange {
+		b, _ := hex.DecodeString(first)
+		return counterRange{first, new(big.Int).SetBytes(b), big.NewInt(length)}
+	}
+	cases := []struct {
+		a, b counterRange
+		want bool
+	}{
+		{r("00000000000000000000000000000000", 4), r("00000000000000000000000000000004", 4), false},
+		{r("00000000000000000000000000000000", 5), r("00000000000000000000000000000004", 4), true},
+		{r("00000000000000000000000000000004", 4), r("00000000000000000000000000000000", 5), true},
+		{r("000000000000000000000000000000ff", 2), r("00000000000000000000000000000100", 1), true},
+		{r("fffffffffffffffffffffffffffffffe", 4), r("00000000000000000000000000000001", 1), true},
+		{r("fffffffffffffffffffffffffffffffe", 2), r("00000000000000000000000000000000", 1), false},
+		{r("0123456789abcdef0123456789abcdef", 1), r("0123456789abcdef0123456789abcdef", 0), false},
+	}
+	for _, c := range cases {
+		if got, _ := overlap(c.a, c.b); got != c.want {
+			t.Errorf("%s+%v and %s+%v: got %t, want %t", c.a.name, c.a.length, c.b.name, c.b.length, got, c.want)
+		}
+	}
+}
+
+// compareBlocks reports the first 16 byte block where got differs from
+// want.
+func compareBlocks(got []byte, want []byte) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("got %d bytes, want %d", len(got), len(want))
+	}
+	for i := 0; i < len(want); i += 16 {
+		end := i + 16
+		if end > len(want) {
+			end = len(want)
+		}
+		if !bytes.Equal(got[i:end], want[i:end]) {
+			return fmt.Errorf("block %d: got %x, want %x", i/16, got[i:end], want[i:end])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"io"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/poly1305"
+)
+
+// macs computes each keyed integrity check by name over the header, IV
+// and encrypted message. The IV is passed separately as well for
+// Poly1305, whose key may only be used once.
+var macs = map[string]func(kMac []byte, IV []byte, data []byte) []byte{
+	"hmac-sha256": hmacSHA256,
+	"poly1305":    poly1305AES,
+	"cmac":        cmacAES,
+}
+
+// macKey derives the MAC key from the encryption key with HKDF-SHA256,
+// one per MAC, so a single key on the command line still keeps encryption
+// and authentication apart.
+func macKey(kEnc []byte, name string) []byte {
+	kMac := make([]byte, 32)
+	_, err := io.ReadFull(hkdf.New(sha256.New, kEnc, nil, []byte("encrypt-auth-chk "+name)), kMac)
+	check(err)
+	return kMac
+}
+
+func hmacSHA256(kMac []byte, IV []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, kMac)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// poly1305AES takes the message's one-time Poly1305 key from the first two
+// blocks of AES-CTR keystream under kMac from the IV, as ChaCha20-Poly1305
+// does with ChaCha20. A fresh IV gives a fresh key.
+func poly1305AES(kMac []byte, IV []byte, data []byte) []byte {
+	block, err := aes.NewCipher(kMac)
+	check(err)
+	var key [32]byte
+	newCTR(block, IV).XORKeyStream(key[:], key[:])
+
+	var tag [16]byte
+	poly1305.Sum(&tag, data, &key)
+	return tag[:]
+}
+
+// cmacAES is AES-CMAC as in RFC 4493, with the first 16 bytes of kMac.
+func cmacAES(kMac []byte, IV []byte, data []byte) []byte {
+	block, err := aes.NewCipher(kMac[0:16])
+	check(err)
+	return encryptauth.CMAC(block, data)
+}
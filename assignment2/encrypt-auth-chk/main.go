@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/hmac"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
 )
 
 func check(e error) {
@@ -15,151 +21,157 @@ func check(e error) {
 	}
 }
 
+// decrypt's errors, printed as they are for decrypt-test-chk to read
+var (
+	errInvalidChecksum = errors.New("INVALID CHECKSUM")
+	errInvalidMAC      = errors.New("INVALID MAC")
+	errInvalidHeader   = errors.New("INVALID HEADER")
+	errMalformed       = errors.New("MALFORMED CIPHERTEXT")
+)
+
 func aesCTREncrypt(kEnc []byte, M []byte) ([]byte, []byte) {
 
 	// Create a 16 byte random nonce
 	nonce := make([]byte, 16)
 	rand.Read(nonce)
 
-	// Create Ciphertext
-	C := make([]byte, 0)
-
-	// Main copy to return
-	ctr := make([]byte, 16)
-	copy(ctr, nonce)
+	return aesCTREncryptNonce(kEnc, M, nonce), nonce
+}
 
-	// intermediate is the enciphered IV
-	intermediate := make([]byte, 16)
+// aesCTREncryptNonce is aesCTREncrypt with a given initial counter block,
+// for the test vectors.
+func aesCTREncryptNonce(kEnc []byte, M []byte, nonce []byte) []byte {
 
 	block, err := aes.NewCipher(kEnc)
 	check(err)
-	block.Encrypt(intermediate, ctr)
-
-	j := 0
-
-	for i := 0; i < len(M); i++ {
 
-		plaintext := M[i]
-		ciphertext := make([]byte, 1)
-
-		ciphertext[0] = intermediate[j] ^ plaintext
-
-		C = append(C, ciphertext...)
-
-		j++
-
-		if j%16 == 0 {
-			ctr[15] += byte(1)
-			block, err := aes.NewCipher(kEnc)
-			check(err)
-			block.Encrypt(intermediate, ctr)
-			j = 0
-		}
-
-	}
-	return C, nonce
+	C := make([]byte, len(M))
+	newCTR(block, nonce).XORKeyStream(C, M)
+	return C
 }
 
+// aesCTRDecrypt is the same keystream XOR as encryption. The nonce is
+// left as it was.
 func aesCTRDecrypt(kEnc []byte, C []byte, nonce []byte) []byte {
+	return aesCTREncryptNonce(kEnc, C, nonce)
+}
 
-	M := make([]byte, 0)
-
-	// intermediate is the enciphered IV
-	intermediate := make([]byte, 16)
+func encrypt(integrity checksum.Integrity, kEnc []byte, M []byte) []byte {
 
-	block, err := aes.NewCipher(kEnc)
-	check(err)
-	block.Encrypt(intermediate, nonce)
+	if integrity.Keyed() {
+		return encryptMAC(integrity, kEnc, M)
+	}
 
-	j := 0
+	// Apply Checksum
+	chksum := integrity.Model.Sum(M)
 
-	for i := 0; i < len(C); i++ {
+	// Compute M′ = Checksum||M
+	M1 := append(chksum, M...)
 
-		ciphertext := C[i]
-		plaintext := make([]byte, 1)
+	// C′ = AES-CTR-ENC(kenc, IV, M′)
+	C1, IV := aesCTREncrypt(kEnc, M1)
 
-		block, err := aes.NewCipher(kEnc)
-		check(err)
-		block.Encrypt(intermediate, nonce)
+	// C = header||IV||C′
+	C := append(checksum.Header(integrity), IV...)
+	C = append(C, C1...)
 
-		plaintext[0] = intermediate[j] ^ ciphertext
+	return C
+}
 
-		M = append(M, plaintext...)
+// encryptMAC is encrypt under a MAC, as Encrypt-then-MAC:
+// C = header||IV||C′||T with T = MAC(kMac, header||IV||C′).
+func encryptMAC(integrity checksum.Integrity, kEnc []byte, M []byte) []byte {
 
-		j++
+	C1, IV := aesCTREncrypt(kEnc, M)
 
-		if j%16 == 0 {
-			nonce[15] += byte(1)
-			block, err := aes.NewCipher(kEnc)
-			check(err)
-			block.Encrypt(intermediate, nonce)
-			j = 0
-		}
-	}
+	C := append(checksum.Header(integrity), IV...)
+	C = append(C, C1...)
+	T := macs[integrity.Name](macKey(kEnc, integrity.Name), IV, C)
 
-	return M
+	return append(C, T...)
 }
 
-func encrypt(kEnc []byte, M []byte) []byte {
+// decrypt only accepts ciphertexts under the check it is told to use.
+// Trusting the header instead would let an attacker relabel a MAC
+// ciphertext as a checksum one and run the checksum attack on it. A
+// ciphertext without a header is in the original format, which only a
+// checksum can be.
+func decrypt(integrity checksum.Integrity, kEnc []byte, C []byte) ([]byte, error) {
 
-	// Apply Checksum
-	var chksum = 0
-	for i := 0; i < len(M); i++ {
-		chksum += int(M[i])
+	named, rest, ok := checksum.SplitHeader(C)
+	if (ok && named.ID != integrity.ID) || (!ok && integrity.Keyed()) {
+		return nil, errInvalidHeader
+	}
+	C = rest
+	if len(C) < 16+integrity.Size {
+		return nil, errMalformed
 	}
 
-	// Calculate Mod
-	chksum = chksum % 256
-
-	temp := make([]byte, 1)
-	temp[0] = byte(chksum)
-
-	// Compute M′ = Checksum||M
-	M1 := append(temp, M...)
-
-	// C′ = AES-CBC-ENC(kenc, IV, M′′)
-	C1, IV := aesCTREncrypt(kEnc, M1)
-
-	// C = (IV ||C′).
-	C := append(IV, C1...)
-
-	return C
-}
-
-func decrypt(kEnc []byte, C []byte) []byte {
+	if integrity.Keyed() {
+		return decryptMAC(integrity, kEnc, C)
+	}
+	m := integrity.Model
 
 	// Parse C = (IV ||C′)
 	IV := make([]byte, 16)
 	copy(IV, C[0:16])
 	C = C[16:]
 
-	// M′′ = AES-CBC-DEC(kenc, IV, C′)
+	// M′ = AES-CTR-DEC(kenc, IV, C′)
 	var M1 = aesCTRDecrypt(kEnc, C, IV)
 
 	// Validate the Checksum
-	chksum := M1[0]
-	temp := 0
-
-	for i := 1; i < len(M1); i++ {
-		temp += int(M1[i])
+	if !bytes.Equal(M1[0:m.Size()], m.Sum(M1[m.Size():])) {
+		return nil, errInvalidChecksum
 	}
-	temp = temp % 256
+	M := M1[m.Size():]
+
+	return M, nil
+}
+
+// decryptMAC checks the tag of IV||C′||T before decrypting anything. decrypt
+// has checked that body is long enough to hold them.
+func decryptMAC(integrity checksum.Integrity, kEnc []byte, body []byte) ([]byte, error) {
 
-	if byte(temp) != chksum {
-		fmt.Print("INVALID CHECKSUM")
-		return nil
+	IV := body[0:16]
+	C1 := body[16 : len(body)-integrity.Size]
+	T := body[len(body)-integrity.Size:]
+
+	header := checksum.Header(integrity)
+	T1 := macs[integrity.Name](macKey(kEnc, integrity.Name), IV, append(append(header, IV...), C1...))
+	if !hmac.Equal(T, T1) {
+		return nil, errInvalidMAC
 	}
-	M := M1[1:]
 
-	return M
+	return aesCTRDecrypt(kEnc, C1, IV), nil
 }
 
 func main() {
 
-	var mode = os.Args[1]
-	var key = os.Args[2]
-	var inputFile = os.Args[3]
-	var outputFile = os.Args[4]
+	integrityPtr := flag.String("integrity", "sum", "integrity check: sum, xor, crc32, hmac-sha256, poly1305 or cmac")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) > 2 && args[0] == "reuse" {
+		if detectReuse(args[1:]) {
+			os.Exit(1)
+		}
+		fmt.Println("no keystream reuse")
+		return
+	}
+	if len(args) != 4 {
+		fmt.Println("usage: encrypt-auth-chk [-integrity sum|xor|crc32|hmac-sha256|poly1305|cmac] <encrypt|decrypt> <hex key> <input file> <output file>")
+		fmt.Println("       encrypt-auth-chk reuse <ciphertext file> <ciphertext file>...")
+		os.Exit(2)
+	}
+
+	integrity, err := checksum.ParseIntegrity(*integrityPtr)
+	check(err)
+
+	var mode = args[0]
+	var key = args[1]
+	var inputFile = args[2]
+	var outputFile = args[3]
 
 	// Read Key
 	kEnc, err := hex.DecodeString(key)
@@ -172,9 +184,12 @@ func main() {
 	// Read mode
 	var result []byte
 	if mode == "encrypt" {
-		result = encrypt(kEnc, text)
+		result = encrypt(integrity, kEnc, text)
 	} else {
-		result = decrypt(kEnc, text)
+		result, err = decrypt(integrity, kEnc, text)
+		if err != nil {
+			fmt.Print(err)
+		}
 	}
 
 	// Write to output File
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+)
+
+// TestRoundTrips encrypts and decrypts random messages under every
+// integrity check.
+func TestRoundTrips(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	for i := 0; i < testRounds; i++ {
+		key := make([]byte, 16+8*rng.Intn(3))
+		M := make([]byte, rng.Intn(2000))
+		rng.Read(key)
+		rng.Read(M)
+		integrity := checksum.Integrities[i%len(checksum.Integrities)]
+		if got, err := decrypt(integrity, key, encrypt(integrity, key, M)); err != nil || !bytes.Equal(got, M) {
+			t.Fatalf("%d byte message does not round trip with %s: %v", len(M), integrity.Name, err)
+		}
+	}
+}
+
+// TestTamper checks that decrypt rejects a MAC ciphertext with any single
+// bit flipped, with its header relabelled as another check or stripped,
+// and truncated.
+func TestTamper(t *testing.T) {
+	for _, integrity := range checksum.Integrities {
+		if !integrity.Keyed() {
+			continue
+		}
+		key := make([]byte, 16)
+		M := []byte("Attack at dawn")
+		C := encrypt(integrity, key, M)
+
+		for i := 0; i < 8*len(C); i++ {
+			forged := append([]byte{}, C...)
+			forged[i/8] ^= 1 << uint(i%8)
+			if _, err := decrypt(integrity, key, forged); err == nil {
+				t.Errorf("%s accepted bit %d flipped", integrity.Name, i)
+			}
+		}
+		for _, other := range checksum.Integrities {
+			forged := append(checksum.Header(other), C[checksum.HeaderSize:]...)
+			if _, err := decrypt(other, key, forged); other.ID != integrity.ID && err == nil {
+				t.Errorf("%s accepted as %s", integrity.Name, other.Name)
+			}
+		}
+		if _, err := decrypt(integrity, key, C[checksum.HeaderSize:]); err == nil {
+			t.Errorf("%s accepted without its header", integrity.Name)
+		}
+		if _, err := decrypt(integrity, key, C[:len(C)-1]); err == nil {
+			t.Errorf("%s accepted truncated", integrity.Name)
+		}
+	}
+}
+
+// TestShortCiphertext checks that decrypt reports ciphertexts too short to
+// hold an IV and a checksum or tag, with and without a header, as
+// malformed rather than slicing past their end.
+func TestShortCiphertext(t *testing.T) {
+	key := make([]byte, 16)
+	for _, integrity := range checksum.Integrities {
+		for n := 0; n < 16+integrity.Size; n++ {
+			body := make([]byte, n)
+			if _, err := decrypt(integrity, key, append(checksum.Header(integrity), body...)); err != errMalformed {
+				t.Errorf("%s, %d bytes after the header: %v, want %v", integrity.Name, n, err, errMalformed)
+			}
+			if integrity.Keyed() || n < checksum.HeaderSize {
+				continue
+			}
+			if _, err := decrypt(integrity, key, body); err != errMalformed {
+				t.Errorf("%s, %d bytes without a header: %v, want %v", integrity.Name, n, err, errMalformed)
+			}
+		}
+	}
+}
@@ -1,240 +1,288 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
-	"crypto/aes"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"math"
 	"os"
-)
-
-func padBytes(bytestream []byte, length int, value int) []byte {
 
-	size := len(bytestream)
-	requiredSize := length - size
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+)
 
-	if requiredSize <= 0 {
-		return bytestream
-	}
+var usage = `
+encrypt-auth [flags] <encrypt|decrypt|encrypt-stream|decrypt-stream|decrypt-legacy> <hex key> <input file> <output file>
+encrypt-auth [flags] -keyfile file | -keyenv var | -passfile file | -passenv var <command> <input file> <output file>
+
+Modes:
+	mte                MAC-then-encrypt, AES-CBC and HMAC-SHA256 (default)
+	etm                Encrypt-then-MAC, AES-CBC and HMAC-SHA256
+	gcm                AES-GCM
+	chacha20poly1305   ChaCha20-Poly1305
+	siv                AES-SIV, deterministic
+	auto               decrypt only: any headered format, named by its header
+
+mte, etm and the streaming commands use AES-CBC with the key size chosen
+by -aes (128, 192 or 256) and HMAC with the hash chosen by -hash (sha256,
+sha384, sha512, sha3-256 or sha3-512); decrypting needs the same choice.
+Their key is the encryption key followed by an equally long MAC key, or
+any other key of at least 16 bytes as a master key the two are derived
+from with HKDF-SHA256. That includes a 16 byte key with the default
+-aes 128, which the original tool took as the encryption key with an
+all-zero MAC key: its ciphertexts under 16 byte keys no longer decrypt.
+gcm takes a 16, 24 or 32 byte key,
+chacha20poly1305 a 32 byte key and siv a 32, 48 or 64 byte key (AES-128,
+AES-192 or AES-256 SIV). Keys are hex encoded.
+Associated data is only supported by the AEAD modes.
+
+The original tool's HMAC-SHA256 got its outer hash wrong, so none of its
+ciphertexts pass the MAC of mte. decrypt-legacy decrypts them, under its
+32 byte or 16 byte keys, and reports any failure as decryption failed;
+nothing is encrypted in that format any more.
+
+To keep the key out of the process list, read it in hex from a file with
+-keyfile or an environment variable with -keyenv. -passfile and -passenv
+take a passphrase instead, stretched to a 32 byte key with the KDF chosen
+by -kdf (pbkdf2, scrypt or argon2id); the KDF, its costs and a random
+salt are stored in front of the ciphertext, so decrypting only needs the
+passphrase.
+
+-hardened decrypts mte ciphertexts in constant time and reports a bad
+length, padding or MAC alike, so decrypt-attack gets nothing to work with.
+
+On failure the error is printed to stdout, no output file is written and
+the exit status tells what went wrong:
+
+	1   other error, e.g. an unreadable file
+	2   usage
+	3   key size
+	4   malformed ciphertext: too short, bad length or bad header
+	5   invalid padding
+	6   invalid MAC
+	7   truncated stream or trailing data
+	8   decryption failed (-hardened)
+
+Flags:
+`
+
+// Exit codes
+const (
+	exitError     = 1
+	exitUsage     = 2
+	exitKeySize   = 3
+	exitMalformed = 4
+	exitPadding   = 5
+	exitMAC       = 6
+	exitTruncated = 7
+	exitFailed    = 8
+)
 
-	temp := make([]byte, requiredSize)
-	for i := range temp {
-		temp[i] = byte(value)
+// exitCode maps an encryptauth error to the exit code documented above.
+func exitCode(err error) int {
+	switch err {
+	case encryptauth.ErrKeySize:
+		return exitKeySize
+	case encryptauth.ErrShortCiphertext, encryptauth.ErrInvalidLength, encryptauth.ErrInvalidHeader:
+		return exitMalformed
+	case encryptauth.ErrInvalidPadding:
+		return exitPadding
+	case encryptauth.ErrInvalidMAC:
+		return exitMAC
+	case encryptauth.ErrTruncated, encryptauth.ErrTrailingData:
+		return exitTruncated
+	case encryptauth.ErrDecryption:
+		return exitFailed
+	case encryptauth.ErrMode, encryptauth.ErrAssociatedData, encryptauth.ErrKeySource, encryptauth.ErrPassphrase:
+		return exitUsage
 	}
-
-	bytestream = append(bytestream, temp...)
-	return bytestream
+	return exitError
 }
 
-func generateBytes(length int, value int) []byte {
-
-	temp := make([]byte, length)
-	for i := range temp {
-		temp[i] = byte(value)
-	}
-	return temp
+// fail prints err to stdout, where decrypt-test has always looked for
+// "INVALID PADDING" and "INVALID MAC", and exits.
+func fail(err error) {
+	fmt.Print(err)
+	os.Exit(exitCode(err))
 }
 
-func hmacSHA256(kMac []byte, M []byte) []byte {
-
-	kMac = padBytes(kMac, 64, 0)
-	outerKey := generateBytes(64, 92)
-	innerKey := generateBytes(64, 54)
-
-	outerKeyPad := make([]byte, 64)
-	innerKeyPad := make([]byte, 64)
+func main() {
 
-	for i := 0; i < 64; i++ {
-		outerKeyPad[i] = outerKey[i] ^ kMac[i]
-		innerKeyPad[i] = innerKey[i] ^ kMac[i]
+	var construction = flag.String("mode", "mte", "construction, see above")
+	var adString = flag.String("ad", "", "associated data")
+	var adFile = flag.String("adfile", "", "file holding the associated data")
+	var hardened = flag.Bool("hardened", false, "decrypt mte in constant time with a single error")
+	var source encryptauth.KeySource
+	source.AddFlags(flag.CommandLine)
+	var kdfName = flag.String("kdf", "argon2id", "KDF for encrypting with a passphrase: pbkdf2, scrypt or argon2id")
+	var aesBits = flag.Int("aes", 128, "AES key size in bits for mte, etm and streams: 128, 192 or 256")
+	var hashName = flag.String("hash", "sha256", "HMAC hash for mte, etm and streams: sha256, sha384, sha512, sha3-256 or sha3-512")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
 	}
-
-	hash := sha256.New()
-	innerKeyPad = append(innerKeyPad, M...)
-	hash.Write(innerKeyPad)
-	innerHash := hash.Sum(nil)
-
-	hash = sha256.New()
-	outerKeyPad = append(innerKeyPad, innerHash...)
-	hash.Write(outerKeyPad)
-
-	return hash.Sum(nil)
-}
-
-func generatePaddingString(M []byte) []byte {
-
-	n := int(math.Mod(float64(len(M)), 16))
-	value := 0
-	length := 16
-	if n != 0 {
-		length = 16 - n
-		value = 16 - n
-	} else {
-		length = 16
-		value = 16
+	flag.Parse()
+
+	// At most one key source; the key argument is only there without one
+	args := flag.Args()
+	if source.Count() == 0 && len(args) == 4 {
+		source.Hex = args[1]
+		args = []string{args[0], args[2], args[3]}
+	} else if source.Count() != 1 || len(args) != 3 {
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
 
-	ps := make([]byte, length)
-	for i := range ps {
-		ps[i] = byte(value)
+	mode, err := encryptauth.ParseMode(*construction)
+	if err != nil {
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
-	return ps
-}
-
-func aesCBCEncrypt(kEnc []byte, M2 []byte) ([]byte, []byte) {
-
-	IV := make([]byte, 16)
-	rand.Read(IV)
-	C := make([]byte, 0)
-
-	var temp = make([]byte, 16)
-	copy(temp, IV)
-	for i := 0; i < len(M2); i += 16 {
-
-		plaintext := M2[i : i+16]
-		for j := 0; j < 16; j++ {
-			temp[j] = temp[j] ^ plaintext[j]
-		}
-
-		block, err := aes.NewCipher(kEnc)
-		check(err)
-		ciphertext := make([]byte, 16)
-		block.Encrypt(ciphertext, temp)
-		temp = ciphertext
-		C = append(C, ciphertext...)
+	kdf, err := encryptauth.ParseKDF(*kdfName)
+	if err != nil {
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
-	return C, IV
-}
-
-func aesCBCDecrypt(kEnc []byte, C []byte, IV []byte) []byte {
-
-	temp := IV
-	M := make([]byte, 0)
-
-	for i := 0; i < len(C); i += 16 {
-
-		ciphertext := C[i : i+16]
-
-		block, err := aes.NewCipher(kEnc)
-		check(err)
-		plaintext := make([]byte, 16)
-		block.Decrypt(plaintext, ciphertext)
-
-		for j := 0; j < 16; j++ {
-			plaintext[j] = plaintext[j] ^ temp[j]
-		}
-		M = append(M, plaintext...)
-		temp = ciphertext
+	suite, err := encryptauth.NewSuite(*aesBits, *hashName)
+	if err != nil {
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
-	return M
-}
-
-func encrypt(kEnc []byte, kMac []byte, M []byte) []byte {
-
-	// Apply HMAC-SHA256
-	var T = hmacSHA256(kMac, M)
 
-	// Compute M′ = M||T
-	var M1 = append(M, T...)
-
-	// M′′ = M′||PS
-	var PS = generatePaddingString(M)
-	var M2 = append(M1, PS...)
-
-	// C′ = AES-CBC-ENC(kenc, IV, M′′)
-	C1, IV := aesCBCEncrypt(kEnc, M2)
-	// C = (IV ||C′).
-	var C = append(IV, C1...)
-
-	return C
-}
-
-func decrypt(kEnc []byte, kMac []byte, C []byte) []byte {
-
-	// Parse C = (IV ||C′)
-	var IV = C[0:16]
-	C = C[16:]
-	// M′′ = AES-CBC-DEC(kenc, IV, C′)
-	var M2 = aesCBCDecrypt(kEnc, C, IV)
-
-	// Validate the message padding
-	lastByte := int(M2[len(M2)-1])
-	if lastByte > len(M2) || lastByte == 0 {
-		fmt.Print("INVALID PADDING")
-		return nil
-	}
-	for i := len(M2) - lastByte; i < len(M2); i++ {
-		if int(M2[i]) != lastByte {
-			fmt.Print("INVALID PADDING")
-			return nil
+	var AD = []byte(*adString)
+	if *adFile != "" {
+		AD, err = ioutil.ReadFile(*adFile)
+		if err != nil {
+			fail(err)
 		}
 	}
-	var M1 = M2[0 : len(M2)-lastByte]
-	// Parse M′ as M||T
-	if len(M1) < 32 {
-		fmt.Print("INVALID MAC")
-		return nil
+	if *hardened && mode != encryptauth.MtE {
+		fmt.Fprintln(os.Stderr, "-hardened only applies to mte")
+		os.Exit(exitUsage)
+	}
+	if len(AD) > 0 && !mode.IsAEAD() && mode != encryptauth.Auto {
+		fmt.Fprintln(os.Stderr, "associated data needs an AEAD mode")
+		os.Exit(exitUsage)
 	}
-	var M = M1[0 : len(M1)-32]
-	var T = M1[len(M1)-32:]
 
-	// Apply the HMAC-SHA256 algorithm
-	var T1 = hmacSHA256(kMac, M)
+	var command = args[0]
+	var inputFile = args[1]
+	var outputFile = args[2]
+	var encrypting = command == "encrypt" || command == "encrypt-stream"
 
-	if bytes.Compare(T1, T) != 0 {
-		fmt.Print("INVALID MAC")
-		return nil
+	key, passphrase, err := source.Read()
+	if err != nil {
+		fail(err)
 	}
-	return M
-}
 
-func check(e error) {
-	if e != nil {
-		panic(e)
+	// params is set when the key comes from a passphrase. Encrypting picks
+	// fresh parameters, decrypting reads them from the input.
+	var params *encryptauth.KDFParams
+	if passphrase != nil && encrypting {
+		params, err = encryptauth.NewKDFParams(kdf)
+		if err != nil {
+			fail(err)
+		}
+		if key, err = params.Key(passphrase); err != nil {
+			fail(err)
+		}
+	}
+	readParams := func(r io.Reader) {
+		if passphrase == nil {
+			return
+		}
+		stored, err := encryptauth.ReadKDFParams(r)
+		if err != nil {
+			fail(err)
+		}
+		if key, err = stored.Key(passphrase); err != nil {
+			fail(err)
+		}
 	}
-}
-
-func main() {
-
-	var mode = os.Args[1]
-	var hexaKey = os.Args[2]
-	var inputFile = os.Args[3]
-	var outputFile = os.Args[4]
-
-	var kEnc = make([]byte, 16)
-	var kMac = make([]byte, 16)
-
-	var key, error = hex.DecodeString(hexaKey)
-	check(error)
 
-	if len(key) == 32 {
-		kEnc = key[0:16]
-		kMac = key[16:32]
-	} else if len(key) == 16 {
-		kEnc = key[0:16]
-	} else {
+	// Streaming modes work through the files a chunk at a time
+	if command == "encrypt-stream" || command == "decrypt-stream" {
+		if mode != encryptauth.MtE || len(AD) > 0 {
+			fmt.Fprintln(os.Stderr, "streaming modes have their own record format, -mode does not apply")
+			os.Exit(exitUsage)
+		}
+		in, err := os.Open(inputFile)
+		if err != nil {
+			fail(err)
+		}
+		defer in.Close()
+		out, err := os.Create(outputFile)
+		if err != nil {
+			fail(err)
+		}
+		w := bufio.NewWriter(out)
+		r := bufio.NewReader(in)
+
+		if command == "encrypt-stream" {
+			if params != nil {
+				w.Write(params.Header())
+			}
+			err = suite.EncryptStream(key, r, w, 0)
+		} else {
+			readParams(r)
+			err = suite.DecryptStream(key, r, w)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		out.Close()
+		if err != nil {
+			// Never leave a partial or unauthenticated plaintext behind
+			os.Remove(outputFile)
+			fail(err)
+		}
 		return
 	}
 
 	// Read Input File
 	text, err := ioutil.ReadFile(inputFile)
-	check(err)
+	if err != nil {
+		fail(err)
+	}
 
 	// Read mode
 	var result []byte
-	if mode == "encrypt" {
-		result = encrypt(kEnc, kMac, text)
-	} else {
-		result = decrypt(kEnc, kMac, text)
+	switch {
+	case command == "encrypt" && mode == encryptauth.Auto:
+		fmt.Fprintln(os.Stderr, "auto only applies to decrypt")
+		os.Exit(exitUsage)
+	case command == "encrypt":
+		result, err = suite.Seal(mode, key, AD, text)
+		if err == nil && params != nil {
+			result = append(params.Header(), result...)
+		}
+	case command == "decrypt-legacy":
+		if passphrase != nil || mode != encryptauth.MtE || *hardened || len(AD) > 0 {
+			fmt.Fprintln(os.Stderr, "decrypt-legacy takes a hex key and no other flags")
+			os.Exit(exitUsage)
+		}
+		result, err = encryptauth.DecryptLegacy(key, text)
+	case command == "decrypt":
+		if passphrase != nil {
+			readParams(bytes.NewReader(text))
+			text = text[encryptauth.KDFHeaderSize:]
+		}
+		if *hardened {
+			result, err = suite.DecryptHardened(key, text)
+		} else {
+			result, err = suite.Open(mode, key, AD, text)
+		}
+	default:
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+	if err != nil {
+		fail(err)
 	}
 
 	// Write to output File
-	f, err := os.Create(outputFile)
-	check(err)
-	f.Write(result)
-	f.Close()
+	if err := ioutil.WriteFile(outputFile, result, 0644); err != nil {
+		fail(err)
+	}
 }
@@ -0,0 +1,85 @@
+package encryptauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AEAD formats:
+//
+//	header | nonce | AEAD-Seal(key, nonce, M, header || AD)
+//
+// The header names the algorithm and is authenticated along with the
+// caller's associated data, so a ciphertext cannot be reinterpreted under
+// another algorithm. AES-SIV takes no nonce and is deterministic.
+
+// newAEAD returns the AEAD for a format. GCM takes a 16, 24 or 32 byte
+// key, ChaCha20-Poly1305 a 32 byte key and AES-SIV a 32, 48 or 64 byte
+// key.
+func newAEAD(format byte, key []byte) (cipher.AEAD, error) {
+	switch format {
+	case formatGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, ErrKeySize
+		}
+		return cipher.NewGCM(block)
+	case formatChaCha20Poly1305:
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, ErrKeySize
+		}
+		return chacha20poly1305.New(key)
+	case formatSIV:
+		return newSIV(key)
+	}
+	return nil, ErrInvalidHeader
+}
+
+func aeadAD(header []byte, AD []byte) []byte {
+	return append(append([]byte{}, header...), AD...)
+}
+
+func encryptAEAD(format byte, key []byte, AD []byte, M []byte) ([]byte, error) {
+	aead, err := newAEAD(format, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := writeHeader(format)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	C := append(header, nonce...)
+	return aead.Seal(C, nonce, M, aeadAD(header, AD)), nil
+}
+
+// decryptAEAD opens C with the algorithm named in its header.
+func decryptAEAD(key []byte, AD []byte, C []byte) ([]byte, error) {
+	if len(C) < headerSize {
+		return nil, ErrInvalidHeader
+	}
+	format := C[3]
+	body, err := parseHeader(C, format)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(format, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrShortCiphertext
+	}
+	nonce := body[:aead.NonceSize()]
+	M, err := aead.Open(nil, nonce, body[aead.NonceSize():], aeadAD(C[:headerSize], AD))
+	if err != nil {
+		return nil, ErrInvalidMAC
+	}
+	return M, nil
+}
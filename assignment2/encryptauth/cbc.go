@@ -0,0 +1,215 @@
+package encryptauth
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/rand"
+	"hash"
+	"math"
+)
+
+func padBytes(bytestream []byte, length int, value int) []byte {
+
+	size := len(bytestream)
+	requiredSize := length - size
+
+	if requiredSize <= 0 {
+		return bytestream
+	}
+
+	temp := make([]byte, requiredSize)
+	for i := range temp {
+		temp[i] = byte(value)
+	}
+
+	bytestream = append(bytestream, temp...)
+	return bytestream
+}
+
+func generateBytes(length int, value int) []byte {
+
+	temp := make([]byte, length)
+	for i := range temp {
+		temp[i] = byte(value)
+	}
+	return temp
+}
+
+// hmacHash is HMAC (RFC 2104) over any hash: H((K ⊕ opad) || H((K ⊕ ipad) || M)),
+// with K padded to the hash's block size, or hashed first if it is longer.
+func hmacHash(h func() hash.Hash, kMac []byte, M []byte) []byte {
+
+	d := h()
+	blockSize := d.BlockSize()
+	if len(kMac) > blockSize {
+		d.Write(kMac)
+		kMac = d.Sum(nil)
+		d.Reset()
+	}
+	kMac = padBytes(append([]byte{}, kMac...), blockSize, 0)
+	outerKey := generateBytes(blockSize, 92)
+	innerKey := generateBytes(blockSize, 54)
+
+	outerKeyPad := make([]byte, blockSize)
+	innerKeyPad := make([]byte, blockSize)
+
+	for i := 0; i < blockSize; i++ {
+		outerKeyPad[i] = outerKey[i] ^ kMac[i]
+		innerKeyPad[i] = innerKey[i] ^ kMac[i]
+	}
+
+	d.Write(innerKeyPad)
+	d.Write(M)
+	innerHash := d.Sum(nil)
+
+	d.Reset()
+	d.Write(outerKeyPad)
+	d.Write(innerHash)
+
+	return d.Sum(nil)
+}
+
+func generatePaddingString(M []byte) []byte {
+
+	n := int(math.Mod(float64(len(M)), 16))
+	value := 0
+	length := 16
+	if n != 0 {
+		length = 16 - n
+		value = 16 - n
+	} else {
+		length = 16
+		value = 16
+	}
+
+	ps := make([]byte, length)
+	for i := range ps {
+		ps[i] = byte(value)
+	}
+	return ps
+}
+
+func aesCBCEncrypt(kEnc []byte, M2 []byte) ([]byte, []byte) {
+
+	IV := make([]byte, 16)
+	rand.Read(IV)
+	return aesCBCEncryptIV(kEnc, M2, IV), IV
+}
+
+// aesCBCEncryptIV is aesCBCEncrypt with a given IV, for the test vectors.
+func aesCBCEncryptIV(kEnc []byte, M2 []byte, IV []byte) []byte {
+
+	C := make([]byte, 0)
+
+	var temp = make([]byte, 16)
+	copy(temp, IV)
+	for i := 0; i < len(M2); i += 16 {
+
+		plaintext := M2[i : i+16]
+		for j := 0; j < 16; j++ {
+			temp[j] = temp[j] ^ plaintext[j]
+		}
+
+		block, err := aes.NewCipher(kEnc)
+		check(err)
+		ciphertext := make([]byte, 16)
+		block.Encrypt(ciphertext, temp)
+		temp = ciphertext
+		C = append(C, ciphertext...)
+	}
+	return C
+}
+
+func aesCBCDecrypt(kEnc []byte, C []byte, IV []byte) []byte {
+
+	temp := IV
+	M := make([]byte, 0)
+
+	for i := 0; i < len(C); i += 16 {
+
+		ciphertext := C[i : i+16]
+
+		block, err := aes.NewCipher(kEnc)
+		check(err)
+		plaintext := make([]byte, 16)
+		block.Decrypt(plaintext, ciphertext)
+
+		for j := 0; j < 16; j++ {
+			plaintext[j] = plaintext[j] ^ temp[j]
+		}
+		M = append(M, plaintext...)
+		temp = ciphertext
+	}
+	return M
+}
+
+// seal is encrypt with additional data: the tag covers AD||M, but only
+// M is encrypted.
+func seal(s Suite, kEnc []byte, kMac []byte, AD []byte, M []byte) []byte {
+
+	// Apply HMAC
+	var T = s.mac(kMac, append(append([]byte{}, AD...), M...))
+
+	// Compute M′ = M||T
+	var M1 = append(append([]byte{}, M...), T...)
+
+	// M′′ = M′||PS
+	var PS = generatePaddingString(M)
+	var M2 = append(M1, PS...)
+
+	// C′ = AES-CBC-ENC(kenc, IV, M′′)
+	C1, IV := aesCBCEncrypt(kEnc, M2)
+	// C = (IV ||C′).
+	var C = append(IV, C1...)
+
+	return C
+}
+
+// open reverses seal, checking the padding and then the tag over AD||M.
+func open(s Suite, kEnc []byte, kMac []byte, AD []byte, C []byte) ([]byte, error) {
+
+	// Parse C = (IV ||C′)
+	if len(C) < 32 {
+		return nil, ErrShortCiphertext
+	}
+	if len(C)%16 != 0 {
+		return nil, ErrInvalidLength
+	}
+	var IV = C[0:16]
+	C = C[16:]
+	// M′′ = AES-CBC-DEC(kenc, IV, C′)
+	var M2 = aesCBCDecrypt(kEnc, C, IV)
+
+	// Validate the message padding
+	lastByte := int(M2[len(M2)-1])
+	if lastByte > len(M2) || lastByte == 0 {
+		return nil, ErrInvalidPadding
+	}
+	for i := len(M2) - lastByte; i < len(M2); i++ {
+		if int(M2[i]) != lastByte {
+			return nil, ErrInvalidPadding
+		}
+	}
+	var M1 = M2[0 : len(M2)-lastByte]
+	// Parse M′ as M||T
+	tagSize := s.TagSize()
+	if len(M1) < tagSize {
+		return nil, ErrInvalidMAC
+	}
+	var M = M1[0 : len(M1)-tagSize]
+	var T = M1[len(M1)-tagSize:]
+
+	// Apply the HMAC algorithm
+	var T1 = s.mac(kMac, append(append([]byte{}, AD...), M...))
+
+	if !hmac.Equal(T1, T) {
+		return nil, ErrInvalidMAC
+	}
+	return M, nil
+}
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
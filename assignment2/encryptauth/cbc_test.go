@@ -0,0 +1,182 @@
+package encryptauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"encoding/hex"
+	mrand "math/rand"
+	"sort"
+	"testing"
+)
+
+// hmacVector is a test case from RFC 4231, with the expected tag for each
+// hash. Test case 5 checks a tag truncated to 128 bits.
+type hmacVector struct {
+	name     string
+	key      []byte
+	data     []byte
+	tags     map[string]string
+	truncate bool
+}
+
+func rfc4231Vectors() []hmacVector {
+	key4 := make([]byte, 25)
+	for i := range key4 {
+		key4[i] = byte(i + 1)
+	}
+	return []hmacVector{
+		{"RFC 4231 test case 1", bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), map[string]string{
+			"sha256": "b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7",
+			"sha384": "afd03944d84895626b0825f4ab46907f15f9dadbe4101ec682aa034c7cebc59cfaea9ea9076ede7f4af152e8b2fa9cb6",
+			"sha512": "87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cdedaa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854",
+		}, false},
+		{"RFC 4231 test case 2", []byte("Jefe"), []byte("what do ya want for nothing?"), map[string]string{
+			"sha256": "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+			"sha384": "af45d2e376484031617f78d2b58a6b1b9c7ef464f5a01b47e42ec3736322445e8e2240ca5e69e2c78b3239ecfab21649",
+			"sha512": "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+		}, false},
+		{"RFC 4231 test case 3", bytes.Repeat([]byte{0xaa}, 20), bytes.Repeat([]byte{0xdd}, 50), map[string]string{
+			"sha256": "773ea91e36800e46854db8ebd09181a72959098b3ef8c122d9635514ced565fe",
+			"sha384": "88062608d3e6ad8a0aa2ace014c8a86f0aa635d947ac9febe83ef4e55966144b2a5ab39dc13814b94e3ab6e101a34f27",
+			"sha512": "fa73b0089d56a284efb0f0756c890be9b1b5dbdd8ee81a3655f83e33b2279d39bf3e848279a722c806b485a47e67c807b946a337bee8942674278859e13292fb",
+		}, false},
+		{"RFC 4231 test case 4", key4, bytes.Repeat([]byte{0xcd}, 50), map[string]string{
+			"sha256": "82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b",
+			"sha384": "3e8a69b7783c25851933ab6290af6ca77a9981480850009cc5577c6e1f573b4e6801dd23c4a7d679ccf8a386c674cffb",
+			"sha512": "b0ba465637458c6990e5a8c5f61d4af7e576d97ff94b872de76f8050361ee3dba91ca5c11aa25eb4d679275cc5788063a5f19741120c4f2de2adebeb10a298dd",
+		}, false},
+		{"RFC 4231 test case 5", bytes.Repeat([]byte{0x0c}, 20), []byte("Test With Truncation"), map[string]string{
+			"sha256": "a3b6167473100ee06e0c796c2955552b",
+			"sha384": "3abf34c3503b2a23a46efc619baef897",
+			"sha512": "415fad6271580a531d4179bc891d87a6",
+		}, true},
+		{"RFC 4231 test case 6", bytes.Repeat([]byte{0xaa}, 131), []byte("Test Using Larger Than Block-Size Key - Hash Key First"), map[string]string{
+			"sha256": "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54",
+			"sha384": "4ece084485813e9088d2c63a041bc5b44f9ef1012a2b588f3cd11f05033ac4c60c2ef6ab4030fe8296248df163f44952",
+			"sha512": "80b24263c7c1a3ebb71493c1dd7be8b49b46d1f41b4aeec1121b013783f8f3526b56d037e05f2598bd0fd2215d6a1e5295e64f73f63f0aec8b915a985d786598",
+		}, false},
+		{"RFC 4231 test case 7", bytes.Repeat([]byte{0xaa}, 131), []byte("This is a test using a larger than block-size key and a larger than block-size data. The key needs to be hashed before being used by the HMAC algorithm."), map[string]string{
+			"sha256": "9b09ffa71b942fcb27635fbcd5b0e944bfdc63644f0713938a7f51535c3a35e2",
+			"sha384": "6617178e941f020d351e2f254e8fd32c602420feb0b8fb9adccebb82461e99c5a678cc31e799176d3860e6110c46523e",
+			"sha512": "e37b6a775dc87dbaa4dfa9f96e5e3ffddebd71f8867289865df5a32d20cdc944b6022cac3c4982b10d5eeb55c3e4de15134676fb6de0446065c97440fa8c6a58",
+		}, false},
+	}
+}
+
+// nistCBCVector is a CBC example from NIST SP 800-38A, appendix F.2. All
+// of them encrypt the same four blocks under the same IV.
+type nistCBCVector struct {
+	name       string
+	key        string
+	ciphertext string
+}
+
+const (
+	nistIV        = "000102030405060708090a0b0c0d0e0f"
+	nistPlaintext = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+)
+
+var nistCBCVectors = []nistCBCVector{
+	{"SP 800-38A F.2.1 CBC-AES128", "2b7e151628aed2a6abf7158809cf4f3c",
+		"7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b2" +
+			"73bed6b8e3c1743b7116e69e222295163ff1caa1681fac09120eca307586e1a7"},
+	{"SP 800-38A F.2.3 CBC-AES192", "8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"4f021db243bc633d7178183a9fa071e8b4d9ada9ad7dedf4e5e738763f69145a" +
+			"571b242012fb7ae07fa9baac3df102e008b0e27988598881d920a9e64f5615cd"},
+	{"SP 800-38A F.2.5 CBC-AES256", "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"f58c4c04d6e5f1ba779eabfb5f7bfbd69cfc4e967edb808d679f777bc6702c7d" +
+			"39f23369a9d9bacfa530e26304231461b2eb05e2c39be9fcda6c19078c6a9d1b"},
+}
+
+// testRounds is the number of random cases each property is checked on.
+// The cases come from a fixed seed, so a failure can be reproduced.
+const testRounds = 200
+
+// macHashNames lists the MAC hashes in a fixed order, so the random cases
+// do not depend on map iteration order.
+func macHashNames() []string {
+	var names []string
+	for name := range macHashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func randomBytes(rng *mrand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rng.Read(b)
+	return b
+}
+
+// TestHMAC checks HMAC against the RFC 4231 vectors, and against
+// crypto/hmac for every hash, including SHA-3 which RFC 4231 does not
+// cover, over random key and message lengths.
+func TestHMAC(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	for _, v := range rfc4231Vectors() {
+		for name, want := range v.tags {
+			got := hmacHash(macHashes[name], v.key, v.data)
+			if v.truncate {
+				got = got[:16]
+			}
+			if hex.EncodeToString(got) != want {
+				t.Fatalf("HMAC-%s %s: got %x, want %s", name, v.name, got, want)
+			}
+		}
+	}
+
+	// Keys shorter than, equal to and longer than every block size
+	for _, name := range macHashNames() {
+		h := macHashes[name]
+		for i := 0; i < testRounds; i++ {
+			key := randomBytes(rng, rng.Intn(300))
+			data := randomBytes(rng, rng.Intn(1000))
+			reference := hmac.New(h, key)
+			reference.Write(data)
+			if !hmac.Equal(hmacHash(h, key, data), reference.Sum(nil)) {
+				t.Fatalf("HMAC-%s with a %d byte key and %d byte message differs from crypto/hmac", name, len(key), len(data))
+			}
+		}
+	}
+}
+
+// TestCBC checks AES-CBC against the NIST SP 800-38A vectors, and
+// against crypto/cipher over random keys, IVs and lengths.
+func TestCBC(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	IV, _ := hex.DecodeString(nistIV)
+	M, _ := hex.DecodeString(nistPlaintext)
+	for _, v := range nistCBCVectors {
+		key, _ := hex.DecodeString(v.key)
+		if got := hex.EncodeToString(aesCBCEncryptIV(key, M, IV)); got != v.ciphertext {
+			t.Fatalf("%s encrypt: got %s, want %s", v.name, got, v.ciphertext)
+		}
+		C, _ := hex.DecodeString(v.ciphertext)
+		if got := aesCBCDecrypt(key, C, IV); !bytes.Equal(got, M) {
+			t.Fatalf("%s decrypt: got %x, want %x", v.name, got, M)
+		}
+	}
+
+	for i := 0; i < testRounds; i++ {
+		key := randomBytes(rng, 16+8*rng.Intn(3))
+		IV := randomBytes(rng, 16)
+		M := randomBytes(rng, 16*rng.Intn(129))
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]byte, len(M))
+		cipher.NewCBCEncrypter(block, IV).CryptBlocks(want, M)
+		C := aesCBCEncryptIV(key, M, IV)
+		if !bytes.Equal(C, want) {
+			t.Fatalf("AES-%d-CBC of %d bytes differs from crypto/cipher", len(key)*8, len(M))
+		}
+		if !bytes.Equal(aesCBCDecrypt(key, C, IV), M) {
+			t.Fatalf("AES-%d-CBC of %d bytes does not decrypt", len(key)*8, len(M))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package encryptauth implements the authenticated encryption
+// constructions used by the encrypt-auth tool: the original AES-CBC with
+// HMAC-SHA256 in MAC-then-encrypt order, Encrypt-then-MAC, a chunked
+// streaming format and the AEAD modes AES-GCM, ChaCha20-Poly1305 and
+// AES-SIV.
+//
+// Keys are given raw or derived from a passphrase with PBKDF2, scrypt or
+// Argon2id, see KDFParams.
+//
+// Every failure is reported as one of the Err values below, so callers
+// can tell a bad key from a forged ciphertext without parsing output.
+package encryptauth
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrKeySize is returned when a key has the wrong length for its mode.
+	ErrKeySize = errors.New("INVALID KEY SIZE")
+
+	// ErrShortCiphertext is returned when a ciphertext is too short to
+	// hold the IV, nonce or tag of its mode.
+	ErrShortCiphertext = errors.New("SHORT CIPHERTEXT")
+
+	// ErrInvalidLength is returned when a CBC ciphertext or a stream
+	// record is not a whole number of blocks.
+	ErrInvalidLength = errors.New("INVALID LENGTH")
+
+	// ErrInvalidPadding is returned when the MAC-then-encrypt or
+	// Encrypt-then-MAC padding is malformed.
+	ErrInvalidPadding = errors.New("INVALID PADDING")
+
+	// ErrInvalidMAC is returned when a tag does not match.
+	ErrInvalidMAC = errors.New("INVALID MAC")
+
+	// ErrInvalidHeader is returned when a headered ciphertext has a bad
+	// magic or version, or names a different mode than the one asked for.
+	ErrInvalidHeader = errors.New("INVALID HEADER")
+
+	// ErrTruncated is returned when a stream ends before its final record.
+	ErrTruncated = errors.New("TRUNCATED STREAM")
+
+	// ErrTrailingData is returned when a stream continues past its final
+	// record.
+	ErrTrailingData = errors.New("TRAILING DATA")
+
+	// ErrAssociatedData is returned when associated data is passed to a
+	// mode that cannot authenticate it.
+	ErrAssociatedData = errors.New("ASSOCIATED DATA NOT SUPPORTED")
+
+	// ErrMode is returned for an unknown mode, or auto when encrypting.
+	ErrMode = errors.New("INVALID MODE")
+)
+
+// Mode selects a construction.
+type Mode string
+
+// Modes. Auto only applies to Open and picks the construction named by
+// the ciphertext's header.
+const (
+	MtE              Mode = "mte"
+	EtM              Mode = "etm"
+	GCM              Mode = "gcm"
+	ChaCha20Poly1305 Mode = "chacha20poly1305"
+	SIV              Mode = "siv"
+	Auto             Mode = "auto"
+)
+
+// ParseMode returns the mode with the given name.
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(name); mode {
+	case MtE, EtM, GCM, ChaCha20Poly1305, SIV, Auto:
+		return mode, nil
+	}
+	return "", ErrMode
+}
+
+// IsAEAD reports whether the mode is one of the AEAD modes, which take
+// their key whole rather than split into encryption and MAC keys.
+func (m Mode) IsAEAD() bool {
+	return m == GCM || m == ChaCha20Poly1305 || m == SIV
+}
+
+// format returns the header format byte of a headered mode.
+func (m Mode) format() byte {
+	switch m {
+	case EtM:
+		return formatEtM
+	case GCM:
+		return formatGCM
+	case ChaCha20Poly1305:
+		return formatChaCha20Poly1305
+	case SIV:
+		return formatSIV
+	}
+	return 0
+}
+
+// Encrypt encrypts M with AES-CBC and HMAC-SHA256 in MAC-then-encrypt
+// order. The key is 16 or 32 bytes, see Seal.
+func Encrypt(key []byte, M []byte) ([]byte, error) {
+	return DefaultSuite.Seal(MtE, key, nil, M)
+}
+
+// Decrypt reverses Encrypt.
+func Decrypt(key []byte, C []byte) ([]byte, error) {
+	return DefaultSuite.Open(MtE, key, nil, C)
+}
+
+// Seal encrypts and authenticates M, and authenticates AD, under the
+// given mode with the default suite. MtE and EtM take a 32 byte encryption
+// key followed by a MAC key, or any other key of at least 16 bytes as a
+// master key; GCM takes a 16, 24 or 32 byte key, ChaCha20Poly1305 a 32
+// byte key and SIV a 32, 48 or 64 byte key. EtM does not support
+// associated data.
+func Seal(mode Mode, key []byte, AD []byte, M []byte) ([]byte, error) {
+	return DefaultSuite.Seal(mode, key, AD, M)
+}
+
+// Open reverses Seal. With Auto, the construction is the one named by
+// C's header; otherwise a headered C must name the given mode.
+func Open(mode Mode, key []byte, AD []byte, C []byte) ([]byte, error) {
+	return DefaultSuite.Open(mode, key, AD, C)
+}
+
+// EncryptStream reads plaintext from r and writes it to w in the chunked
+// streaming format, chunkSize plaintext bytes per record (0 for the
+// default, at most 16MiB). The key is split as for MtE.
+func EncryptStream(key []byte, r io.Reader, w io.Writer, chunkSize int) error {
+	return DefaultSuite.EncryptStream(key, r, w, chunkSize)
+}
+
+// DecryptStream reverses EncryptStream. Plaintext is written to w as each
+// record is verified, so on error w holds a verified prefix of the
+// plaintext that the caller should discard.
+func DecryptStream(key []byte, r io.Reader, w io.Writer) error {
+	return DefaultSuite.DecryptStream(key, r, w)
+}
+
+// Seal is Seal with the suite s. MtE and EtM take a key of twice the
+// suite's AES key size, the encryption key followed by the MAC key, or a
+// master key of any other length from 16 bytes up.
+func (s Suite) Seal(mode Mode, key []byte, AD []byte, M []byte) ([]byte, error) {
+	if mode.IsAEAD() {
+		return encryptAEAD(mode.format(), key, AD, M)
+	}
+	if mode != MtE && mode != EtM {
+		return nil, ErrMode
+	}
+	kEnc, kMac, err := s.splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if mode == EtM {
+		if len(AD) > 0 {
+			return nil, ErrAssociatedData
+		}
+		return encryptEtM(s, kEnc, kMac, M), nil
+	}
+	return seal(s, kEnc, kMac, AD, M), nil
+}
+
+// Open is Open with the suite s.
+func (s Suite) Open(mode Mode, key []byte, AD []byte, C []byte) ([]byte, error) {
+	if mode == MtE {
+		kEnc, kMac, err := s.splitKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return open(s, kEnc, kMac, AD, C)
+	}
+
+	format := headerFormat(C)
+	switch {
+	case mode == Auto:
+	case mode.format() == 0:
+		return nil, ErrMode
+	case format != mode.format():
+		return nil, ErrInvalidHeader
+	}
+
+	if format == formatEtM {
+		if len(AD) > 0 {
+			return nil, ErrAssociatedData
+		}
+		kEnc, kMac, err := s.splitKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return decryptEtM(s, kEnc, kMac, C)
+	}
+	return decryptAEAD(key, AD, C)
+}
+
+// EncryptStream is EncryptStream with the suite s.
+func (s Suite) EncryptStream(key []byte, r io.Reader, w io.Writer, chunkSize int) error {
+	kEnc, kMac, err := s.splitKey(key)
+	if err != nil {
+		return err
+	}
+	if chunkSize == 0 {
+		chunkSize = streamChunkSize
+	}
+	if chunkSize < 0 || chunkSize > streamMaxChunkSize {
+		return ErrInvalidLength
+	}
+	return encryptStream(s, kEnc, kMac, r, w, chunkSize)
+}
+
+// DecryptStream is DecryptStream with the suite s.
+func (s Suite) DecryptStream(key []byte, r io.Reader, w io.Writer) error {
+	kEnc, kMac, err := s.splitKey(key)
+	if err != nil {
+		return err
+	}
+	return decryptStream(s, kEnc, kMac, r, w)
+}
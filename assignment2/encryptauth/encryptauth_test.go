@@ -0,0 +1,196 @@
+package encryptauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	mrand "math/rand"
+	"testing"
+)
+
+// referenceKey is a 32 byte key, which MtE splits into kEnc || kMac as
+// the original tool did.
+var referenceKey = bytes.Repeat([]byte{0x2b, 0x7e, 0x15, 0x16}, 8)
+
+// referenceMessages cover empty, block aligned and unaligned lengths.
+var referenceMessages = [][]byte{
+	nil,
+	[]byte("a"),
+	[]byte("exactly sixteen!"),
+	bytes.Repeat([]byte("attack at dawn "), 7),
+}
+
+// referenceSeal builds the MtE layout with the standard library:
+// IV || AES-CBC(kEnc, IV, M || HMAC-SHA256(kMac, M) || PKCS#7 padding).
+// It is the original tool's layout, but with a correct HMAC; the original
+// tool's own ciphertexts are checked in legacy_test.go.
+func referenceSeal(t *testing.T, key []byte, M []byte) []byte {
+	mac := hmac.New(sha256.New, key[16:])
+	mac.Write(M)
+	M2 := append(append([]byte{}, M...), mac.Sum(nil)...)
+	n := 16 - len(M2)%16
+	M2 = append(M2, bytes.Repeat([]byte{byte(n)}, n)...)
+
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	C := make([]byte, 16+len(M2))
+	if _, err := rand.Read(C[:16]); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCEncrypter(block, C[:16]).CryptBlocks(C[16:], M2)
+	return C
+}
+
+// referenceOpen reverses referenceSeal with the standard library.
+func referenceOpen(t *testing.T, key []byte, C []byte) []byte {
+	if len(C) < 48 || len(C)%16 != 0 {
+		t.Fatalf("%d byte ciphertext is not IV || whole blocks", len(C))
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	M2 := make([]byte, len(C)-16)
+	cipher.NewCBCDecrypter(block, C[:16]).CryptBlocks(M2, C[16:])
+
+	n := int(M2[len(M2)-1])
+	if n < 1 || n > 16 || !bytes.Equal(M2[len(M2)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		t.Fatalf("bad PKCS#7 padding %x", M2[len(M2)-16:])
+	}
+	M1 := M2[:len(M2)-n]
+	if len(M1) < 32 {
+		t.Fatalf("no room for the tag in %d bytes", len(M1))
+	}
+	M, T := M1[:len(M1)-32], M1[len(M1)-32:]
+	mac := hmac.New(sha256.New, key[16:])
+	mac.Write(M)
+	if !hmac.Equal(T, mac.Sum(nil)) {
+		t.Fatalf("tag %x does not match HMAC-SHA256", T)
+	}
+	return M
+}
+
+func TestMtEMatchesReferenceLayout(t *testing.T) {
+	for _, M := range referenceMessages {
+		C, err := Seal(MtE, referenceKey, nil, M)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 16 + (len(M)+32)/16*16 + 16; len(C) != want {
+			t.Errorf("%d byte message: %d byte ciphertext, want %d", len(M), len(C), want)
+		}
+		if got := referenceOpen(t, referenceKey, C); !bytes.Equal(got, M) {
+			t.Errorf("reference decrypt of Seal(MtE) = %q, want %q", got, M)
+		}
+
+		C = referenceSeal(t, referenceKey, M)
+		for name, open := range map[string]func([]byte, []byte) ([]byte, error){
+			"Open(MtE)": func(key []byte, C []byte) ([]byte, error) { return Open(MtE, key, nil, C) },
+			"Decrypt":   Decrypt,
+		} {
+			got, err := open(referenceKey, C)
+			if err != nil {
+				t.Errorf("%s of a reference ciphertext: %v", name, err)
+			} else if !bytes.Equal(got, M) {
+				t.Errorf("%s of a reference ciphertext = %q, want %q", name, got, M)
+			}
+		}
+	}
+}
+
+func TestMtERejectedByHeaderedModes(t *testing.T) {
+	for _, M := range referenceMessages {
+		C := referenceSeal(t, referenceKey, M)
+		if headerFormat(C) != 0 {
+			// The random IV happens to start with a header
+			continue
+		}
+		for _, mode := range []Mode{EtM, GCM, ChaCha20Poly1305, SIV, Auto} {
+			if got, err := Open(mode, referenceKey, nil, C); err != ErrInvalidHeader {
+				t.Errorf("Open(%s) of a %d byte MtE ciphertext = %q, %v, want %v", mode, len(M), got, err, ErrInvalidHeader)
+			}
+		}
+	}
+}
+
+func TestHeaderedRejectedByMtE(t *testing.T) {
+	for _, M := range referenceMessages {
+		for _, mode := range []Mode{EtM, GCM, ChaCha20Poly1305, SIV} {
+			C, err := Seal(mode, referenceKey, nil, M)
+			if err != nil {
+				t.Fatalf("Seal(%s): %v", mode, err)
+			}
+			if got, err := Open(MtE, referenceKey, nil, C); err == nil {
+				t.Errorf("Open(MtE) of a %d byte %s ciphertext = %q, want an error", len(M), mode, got)
+			}
+		}
+	}
+}
+
+// TestRoundTrips encrypts and decrypts with every mode and suite over
+// random message lengths, and checks a flipped ciphertext bit is rejected.
+func TestRoundTrips(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	var suites []Suite
+	for _, bits := range []int{128, 192, 256} {
+		for _, name := range macHashNames() {
+			s, err := NewSuite(bits, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			suites = append(suites, s)
+		}
+	}
+
+	for i := 0; i < testRounds; i++ {
+		s := suites[rng.Intn(len(suites))]
+		M := randomBytes(rng, rng.Intn(200))
+		modes := []Mode{MtE, EtM, GCM, ChaCha20Poly1305, SIV}
+		mode := modes[rng.Intn(len(modes))]
+		key := randomBytes(rng, 2*s.KeySize)
+		if mode.IsAEAD() {
+			key = randomBytes(rng, 32)
+		}
+		var AD []byte
+		if mode != EtM {
+			AD = randomBytes(rng, rng.Intn(20))
+		}
+
+		C, err := s.Seal(mode, key, AD, M)
+		if err != nil {
+			t.Fatalf("%s %s: %v", s, mode, err)
+		}
+		if got, err := s.Open(mode, key, AD, C); err != nil || !bytes.Equal(got, M) {
+			t.Fatalf("%s %s: %d byte round trip failed: %v", s, mode, len(M), err)
+		}
+		if mode == MtE {
+			if got, err := s.OpenHardened(key, AD, C); err != nil || !bytes.Equal(got, M) {
+				t.Fatalf("%s hardened: %d byte round trip failed: %v", s, len(M), err)
+			}
+		}
+
+		C[rng.Intn(len(C))] ^= 1 << uint(rng.Intn(8))
+		if _, err := s.Open(mode, key, AD, C); err == nil {
+			t.Fatalf("%s %s: accepted a modified %d byte ciphertext", s, mode, len(M))
+		}
+	}
+
+	// Streams, with chunks small enough to give several records
+	for i := 0; i < testRounds/10; i++ {
+		s := suites[rng.Intn(len(suites))]
+		key := randomBytes(rng, 2*s.KeySize)
+		M := randomBytes(rng, rng.Intn(2000))
+		var C, got bytes.Buffer
+		if err := s.EncryptStream(key, bytes.NewReader(M), &C, 1+rng.Intn(300)); err != nil {
+			t.Fatalf("%s stream: %v", s, err)
+		}
+		if err := s.DecryptStream(key, &C, &got); err != nil || !bytes.Equal(got.Bytes(), M) {
+			t.Fatalf("%s stream: %d byte round trip failed: %v", s, len(M), err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package encryptauth
+
+import (
+	"crypto/subtle"
+)
+
+// Encrypt-then-MAC format:
+//
+//	header | IV | AES-CBC(kEnc, IV, M || PS) | T
+//	T = HMAC(kMac, header || IV || C′)
+//
+// The tag is checked before anything is decrypted, so a forged or
+// modified ciphertext is rejected without ever looking at its padding and
+// the padding oracle used by decrypt-attack never comes into play.
+
+func encryptEtM(s Suite, kEnc []byte, kMac []byte, M []byte) []byte {
+
+	// M′ = M||PS
+	var PS = generatePaddingString(M)
+	var M1 = append(append([]byte{}, M...), PS...)
+
+	// C′ = AES-CBC-ENC(kenc, IV, M′)
+	C1, IV := aesCBCEncrypt(kEnc, M1)
+
+	// C = header||IV||C′||T
+	var C = writeHeader(formatEtM)
+	C = append(C, IV...)
+	C = append(C, C1...)
+	var T = s.mac(kMac, C)
+
+	return append(C, T...)
+}
+
+func decryptEtM(s Suite, kEnc []byte, kMac []byte, C []byte) ([]byte, error) {
+
+	body, err := parseHeader(C, formatEtM)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse C = header||IV||C′||T, with at least one block in C′
+	tagSize := s.TagSize()
+	if len(body) < 16+16+tagSize {
+		return nil, ErrShortCiphertext
+	}
+	if (len(body)-tagSize)%16 != 0 {
+		return nil, ErrInvalidLength
+	}
+	var T = C[len(C)-tagSize:]
+	var IV = body[0:16]
+	var C1 = body[16 : len(body)-tagSize]
+
+	// Verify the tag first
+	var T1 = s.mac(kMac, C[:len(C)-tagSize])
+	if subtle.ConstantTimeCompare(T1, T) != 1 {
+		return nil, ErrInvalidMAC
+	}
+
+	// M′ = AES-CBC-DEC(kenc, IV, C′)
+	var M1 = aesCBCDecrypt(kEnc, C1, IV)
+
+	// The ciphertext is authentic, so bad padding means a broken encryptor
+	lastByte := int(M1[len(M1)-1])
+	if lastByte > 16 || lastByte == 0 {
+		return nil, ErrInvalidPadding
+	}
+	for i := len(M1) - lastByte; i < len(M1); i++ {
+		if int(M1[i]) != lastByte {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return M1[0 : len(M1)-lastByte], nil
+}
@@ -0,0 +1,93 @@
+package encryptauth
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// ErrDecryption is the only error the hardened MAC-then-encrypt path
+// returns for a bad ciphertext, whether its length, padding or tag is
+// wrong.
+var ErrDecryption = errors.New("DECRYPTION FAILED")
+
+// openHardened is open without the padding oracle. open stops at the
+// first bad padding byte and only computes the tag for well padded
+// ciphertexts, so both its answer and its running time tell an attacker
+// whether the padding was valid. Here every step does the same work
+// whatever the plaintext:
+//
+//   - the padding is checked over the whole last block with masks;
+//   - the tag is computed for each of the 16 possible padding lengths and
+//     the right one is picked with subtle.ConstantTimeCopy, so the number
+//     of hashed bytes does not depend on the padding;
+//   - the tag is compared with subtle.ConstantTimeCompare, and padding
+//     and MAC failures are folded into a single result.
+//
+// Computing 16 tags makes it about 16 times slower than open.
+func openHardened(s Suite, kEnc []byte, kMac []byte, AD []byte, C []byte) ([]byte, error) {
+
+	// The shortest ciphertext is IV || E(T || 16 bytes of padding). The
+	// length is public, so checking it early leaks nothing.
+	tagSize := s.TagSize()
+	if len(C) < 16+tagSize+16 || len(C)%16 != 0 {
+		return nil, ErrDecryption
+	}
+	var M2 = aesCBCDecrypt(kEnc, C[16:], C[0:16])
+	L := len(M2)
+
+	// Padding: 1 <= p <= 16 and the last p bytes all equal p
+	p := int(M2[L-1])
+	good := subtle.ConstantTimeLessOrEq(1, p) & subtle.ConstantTimeLessOrEq(p, 16)
+	for i := 0; i < 16; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i+1, p)
+		good &= subtle.ConstantTimeSelect(inPadding, subtle.ConstantTimeByteEq(M2[L-1-i], byte(p)), 1)
+	}
+
+	// Tag: for every padding length, hash the message it would leave and
+	// keep the one matching p
+	T := make([]byte, tagSize)
+	T1 := make([]byte, tagSize)
+	mLen := 0
+	for n := 1; n <= 16; n++ {
+		end := L - n - tagSize
+		selected := subtle.ConstantTimeEq(int32(n), int32(p))
+		subtle.ConstantTimeCopy(selected, T1, s.mac(kMac, append(append([]byte{}, AD...), M2[:end]...)))
+		subtle.ConstantTimeCopy(selected, T, M2[end:end+tagSize])
+		mLen = subtle.ConstantTimeSelect(selected, end, mLen)
+	}
+
+	// With bad padding no length is selected and T and T1 are both zero,
+	// so good must be checked as well
+	if good&subtle.ConstantTimeCompare(T1, T) != 1 {
+		return nil, ErrDecryption
+	}
+	return M2[:mLen], nil
+}
+
+// DecryptHardened reverses Encrypt like Decrypt, but checks the padding
+// and tag in constant time and reports every failure as ErrDecryption,
+// so it cannot be used as a padding oracle. A key of the wrong length is
+// still reported as ErrKeySize.
+func DecryptHardened(key []byte, C []byte) ([]byte, error) {
+	return DefaultSuite.OpenHardened(key, nil, C)
+}
+
+// OpenHardened is Open(MtE, key, AD, C) on the hardened path, see
+// DecryptHardened.
+func OpenHardened(key []byte, AD []byte, C []byte) ([]byte, error) {
+	return DefaultSuite.OpenHardened(key, AD, C)
+}
+
+// DecryptHardened is DecryptHardened with the suite s.
+func (s Suite) DecryptHardened(key []byte, C []byte) ([]byte, error) {
+	return s.OpenHardened(key, nil, C)
+}
+
+// OpenHardened is OpenHardened with the suite s.
+func (s Suite) OpenHardened(key []byte, AD []byte, C []byte) ([]byte, error) {
+	kEnc, kMac, err := s.splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return openHardened(s, kEnc, kMac, AD, C)
+}
@@ -0,0 +1,49 @@
+package encryptauth
+
+import (
+	"bytes"
+)
+
+// Ciphertext formats other than the original MAC-then-encrypt one start
+// with a four byte header: the magic "EA", a version and a format byte
+// telling the reader what follows.
+var headerMagic = []byte("EA")
+
+const headerVersion = 1
+
+const headerSize = 4
+
+// Formats recorded in the header
+const (
+	formatStream byte = 1
+	formatEtM    byte = 2
+
+	formatGCM              byte = 3
+	formatChaCha20Poly1305 byte = 4
+	formatSIV              byte = 5
+
+	formatPassphrase byte = 6
+)
+
+func writeHeader(format byte) []byte {
+	return append(append([]byte{}, headerMagic...), headerVersion, format)
+}
+
+// headerFormat returns the format named by C's header, or 0 if C does
+// not start with a valid header.
+func headerFormat(C []byte) byte {
+	if len(C) < headerSize || !bytes.Equal(C[0:2], headerMagic) || C[2] != headerVersion {
+		return 0
+	}
+	return C[3]
+}
+
+// parseHeader checks that C starts with a header for the given format and
+// returns the rest of C.
+func parseHeader(C []byte, format byte) ([]byte, error) {
+	if len(C) < headerSize || !bytes.Equal(C[0:2], headerMagic) ||
+		C[2] != headerVersion || C[3] != format {
+		return nil, ErrInvalidHeader
+	}
+	return C[headerSize:], nil
+}
@@ -0,0 +1,199 @@
+package encryptauth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Passphrase format:
+//
+//	header | kdf (1) | time (4) | memory (4) | threads (1) | salt (16) | C
+//
+// C is the ciphertext of any mode under the key the KDF derives from the
+// passphrase. The parameters are not authenticated separately: changing
+// any of them changes the key, so C fails its MAC.
+
+// KDF selects a password-based key derivation function.
+type KDF byte
+
+// KDFs. The meaning of KDFParams.Time, Memory and Threads depends on the
+// function:
+//
+//	PBKDF2     iterations of HMAC-SHA256, -, -
+//	Scrypt     log2 N, r, p
+//	Argon2id   passes, memory in KiB, threads
+const (
+	PBKDF2   KDF = 1
+	Scrypt   KDF = 2
+	Argon2id KDF = 3
+)
+
+var kdfNames = map[string]KDF{"pbkdf2": PBKDF2, "scrypt": Scrypt, "argon2id": Argon2id}
+
+const kdfSaltSize = 16
+
+// KDFHeaderSize is the length of the header and parameters in front of
+// a passphrase encrypted ciphertext.
+const KDFHeaderSize = headerSize + 1 + 4 + 4 + 1 + kdfSaltSize
+
+// ParseKDF returns the KDF with the given name.
+func ParseKDF(name string) (KDF, error) {
+	if kdf, ok := kdfNames[name]; ok {
+		return kdf, nil
+	}
+	return 0, ErrMode
+}
+
+// KDFParams are the KDF, costs and salt stored with a passphrase
+// encrypted ciphertext.
+type KDFParams struct {
+	KDF     KDF
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	Salt    []byte
+}
+
+// NewKDFParams returns the default costs for a KDF with a fresh random
+// salt.
+func NewKDFParams(kdf KDF) (*KDFParams, error) {
+	p := &KDFParams{KDF: kdf, Salt: make([]byte, kdfSaltSize)}
+	switch kdf {
+	case PBKDF2:
+		p.Time = 600000
+	case Scrypt:
+		p.Time, p.Memory, p.Threads = 15, 8, 1
+	case Argon2id:
+		p.Time, p.Memory, p.Threads = 3, 64*1024, 4
+	default:
+		return nil, ErrMode
+	}
+	if _, err := rand.Read(p.Salt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Largest costs a reader accepts: a few seconds of PBKDF2, and at most
+// 1GiB of memory for scrypt (128·r·N bytes) and Argon2id.
+const (
+	maxPBKDF2Iterations = 2000000
+	maxScryptMemory     = 1 << 30
+	maxScryptThreads    = 4
+)
+
+// valid reports whether the parameters name a known KDF with costs a
+// reader is willing to pay, so a forged header cannot make it spend
+// minutes or gigabytes before the MAC check.
+func (p *KDFParams) valid() bool {
+	if len(p.Salt) != kdfSaltSize {
+		return false
+	}
+	switch p.KDF {
+	case PBKDF2:
+		return p.Time >= 1 && p.Time <= maxPBKDF2Iterations
+	case Scrypt:
+		return p.Time >= 1 && p.Time < 30 && p.Memory >= 1 && p.Memory <= maxScryptMemory/128 &&
+			128*uint64(p.Memory)<<p.Time <= maxScryptMemory &&
+			p.Threads >= 1 && p.Threads <= maxScryptThreads
+	case Argon2id:
+		return p.Time >= 1 && p.Time <= 16 && p.Memory >= 8*uint32(p.Threads) &&
+			p.Memory <= 1024*1024 && p.Threads >= 1
+	}
+	return false
+}
+
+// Key derives a 32 byte key from a passphrase. It serves every mode: the
+// CBC modes split it into encryption and MAC keys or, unless the suite
+// uses AES-128, expand it with HKDF; GCM uses AES-256 and SIV AES-128.
+func (p *KDFParams) Key(passphrase []byte) ([]byte, error) {
+	const size = 32
+	if !p.valid() {
+		return nil, ErrInvalidHeader
+	}
+	switch p.KDF {
+	case PBKDF2:
+		return pbkdf2.Key(passphrase, p.Salt, int(p.Time), size, sha256.New), nil
+	case Scrypt:
+		return scrypt.Key(passphrase, p.Salt, 1<<p.Time, int(p.Memory), int(p.Threads), size)
+	}
+	return argon2.IDKey(passphrase, p.Salt, p.Time, p.Memory, p.Threads, uint32(size)), nil
+}
+
+// Header returns the header and parameters that start a passphrase
+// encrypted ciphertext.
+func (p *KDFParams) Header() []byte {
+	b := writeHeader(formatPassphrase)
+	b = append(b, byte(p.KDF))
+	b = binary.BigEndian.AppendUint32(b, p.Time)
+	b = binary.BigEndian.AppendUint32(b, p.Memory)
+	b = append(b, p.Threads)
+	return append(b, p.Salt...)
+}
+
+// ReadKDFParams reads the header and parameters written by Header.
+func ReadKDFParams(r io.Reader) (*KDFParams, error) {
+	b := make([]byte, KDFHeaderSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrInvalidHeader
+	}
+	b, err := parseHeader(b, formatPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	p := &KDFParams{
+		KDF:     KDF(b[0]),
+		Time:    binary.BigEndian.Uint32(b[1:5]),
+		Memory:  binary.BigEndian.Uint32(b[5:9]),
+		Threads: b[9],
+		Salt:    b[10:],
+	}
+	if !p.valid() {
+		return nil, ErrInvalidHeader
+	}
+	return p, nil
+}
+
+// SealPassphrase is Seal with a key derived from a passphrase. The KDF
+// parameters are stored in front of the ciphertext.
+func SealPassphrase(mode Mode, passphrase []byte, params *KDFParams, AD []byte, M []byte) ([]byte, error) {
+	return DefaultSuite.SealPassphrase(mode, passphrase, params, AD, M)
+}
+
+// OpenPassphrase reverses SealPassphrase.
+func OpenPassphrase(mode Mode, passphrase []byte, AD []byte, C []byte) ([]byte, error) {
+	return DefaultSuite.OpenPassphrase(mode, passphrase, AD, C)
+}
+
+// SealPassphrase is SealPassphrase with the suite s.
+func (s Suite) SealPassphrase(mode Mode, passphrase []byte, params *KDFParams, AD []byte, M []byte) ([]byte, error) {
+	key, err := params.Key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	C, err := s.Seal(mode, key, AD, M)
+	if err != nil {
+		return nil, err
+	}
+	return append(params.Header(), C...), nil
+}
+
+// OpenPassphrase is OpenPassphrase with the suite s.
+func (s Suite) OpenPassphrase(mode Mode, passphrase []byte, AD []byte, C []byte) ([]byte, error) {
+	params, err := ReadKDFParams(bytes.NewReader(C))
+	if err != nil {
+		return nil, err
+	}
+	key, err := params.Key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return s.Open(mode, key, AD, C[KDFHeaderSize:])
+}
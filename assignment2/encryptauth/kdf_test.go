@@ -0,0 +1,41 @@
+package encryptauth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKDFParamsCostBounds(t *testing.T) {
+	salt := make([]byte, kdfSaltSize)
+	for _, c := range []struct {
+		p     KDFParams
+		valid bool
+	}{
+		{KDFParams{PBKDF2, 600000, 0, 0, salt}, true},
+		{KDFParams{PBKDF2, maxPBKDF2Iterations, 0, 0, salt}, true},
+		{KDFParams{PBKDF2, maxPBKDF2Iterations + 1, 0, 0, salt}, false},
+		{KDFParams{PBKDF2, 10000000, 0, 0, salt}, false},
+		{KDFParams{Scrypt, 15, 8, 1, salt}, true},
+		{KDFParams{Scrypt, 20, 8, 4, salt}, true},  // 1GiB
+		{KDFParams{Scrypt, 21, 8, 1, salt}, false}, // 2GiB
+		{KDFParams{Scrypt, 22, 32, 16, salt}, false},
+		{KDFParams{Scrypt, 15, 8, maxScryptThreads + 1, salt}, false},
+		{KDFParams{Scrypt, 29, 1 << 20, 1, salt}, false},
+		{KDFParams{Scrypt, 1<<32 - 1, 1, 1, salt}, false},
+		{KDFParams{Argon2id, 3, 64 * 1024, 4, salt}, true},
+		{KDFParams{Argon2id, 3, 2 * 1024 * 1024, 4, salt}, false},
+		{KDFParams{PBKDF2, 1000, 0, 0, salt[:8]}, false},
+	} {
+		if got := c.p.valid(); got != c.valid {
+			t.Errorf("%+v valid = %v, want %v", c.p, got, c.valid)
+		}
+	}
+}
+
+func TestForgedKDFHeaderRejected(t *testing.T) {
+	params := &KDFParams{KDF: Scrypt, Time: 22, Memory: 32, Threads: 16, Salt: make([]byte, kdfSaltSize)}
+	C := append(params.Header(), bytes.Repeat([]byte{0}, 64)...)
+	if _, err := OpenPassphrase(MtE, []byte("passphrase"), nil, C); err != ErrInvalidHeader {
+		t.Errorf("OpenPassphrase with a 16GiB scrypt header: %v, want %v", err, ErrInvalidHeader)
+	}
+}
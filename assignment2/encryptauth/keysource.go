@@ -0,0 +1,101 @@
+package encryptauth
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrKeySource is returned by KeySource.Read when no key source is
+	// set, or more than one.
+	ErrKeySource = errors.New("NEED EXACTLY ONE KEY SOURCE")
+
+	// ErrPassphrase is returned by KeySource.Read for an empty
+	// passphrase.
+	ErrPassphrase = errors.New("EMPTY PASSPHRASE")
+)
+
+// KeySource is where a tool reads its key from: a hex key given on the
+// command line, which shows in the process list, a hex key in a file or
+// an environment variable, or a passphrase in a file or an environment
+// variable. Exactly one may be set.
+type KeySource struct {
+	Hex      string
+	KeyFile  string
+	KeyEnv   string
+	PassFile string
+	PassEnv  string
+}
+
+// AddFlags registers -keyfile, -keyenv, -passfile and -passenv on flags.
+// Where the hex key itself comes from is up to the tool.
+func (k *KeySource) AddFlags(flags *flag.FlagSet) {
+	flags.StringVar(&k.KeyFile, "keyfile", "", "file holding the hex key")
+	flags.StringVar(&k.KeyEnv, "keyenv", "", "environment variable holding the hex key")
+	flags.StringVar(&k.PassFile, "passfile", "", "file holding the passphrase")
+	flags.StringVar(&k.PassEnv, "passenv", "", "environment variable holding the passphrase")
+}
+
+// Count returns how many sources are set.
+func (k *KeySource) Count() int {
+	n := 0
+	for _, source := range []string{k.Hex, k.KeyFile, k.KeyEnv, k.PassFile, k.PassEnv} {
+		if source != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Read returns the hex decoded key or the passphrase, whichever the
+// source holds; the other is nil. A file loses the trailing newline an
+// editor leaves behind.
+func (k *KeySource) Read() ([]byte, []byte, error) {
+	if k.Count() != 1 {
+		return nil, nil, ErrKeySource
+	}
+
+	if k.PassFile != "" || k.PassEnv != "" {
+		passphrase := os.Getenv(k.PassEnv)
+		if k.PassFile != "" {
+			secret, err := readSecret(k.PassFile)
+			if err != nil {
+				return nil, nil, err
+			}
+			passphrase = secret
+		}
+		if passphrase == "" {
+			return nil, nil, ErrPassphrase
+		}
+		return nil, []byte(passphrase), nil
+	}
+
+	hexaKey := k.Hex
+	switch {
+	case k.KeyFile != "":
+		secret, err := readSecret(k.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		hexaKey = secret
+	case k.KeyEnv != "":
+		hexaKey = os.Getenv(k.KeyEnv)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(hexaKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, nil, nil
+}
+
+func readSecret(name string) (string, error) {
+	secret, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(secret), "\r\n"), nil
+}
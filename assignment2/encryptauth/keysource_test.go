@@ -0,0 +1,60 @@
+package encryptauth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeySource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keysource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key.hex")
+	passFile := filepath.Join(dir, "pass.txt")
+	emptyFile := filepath.Join(dir, "empty.txt")
+	for name, data := range map[string]string{keyFile: "000102030405060708090a0b0c0d0e0f\n", passFile: "correct horse\r\n", emptyFile: "\n"} {
+		if err := ioutil.WriteFile(name, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Setenv("KEYSOURCE_TEST_KEY", "000102030405060708090a0b0c0d0e0f")
+	os.Setenv("KEYSOURCE_TEST_PASS", "correct horse")
+	defer os.Unsetenv("KEYSOURCE_TEST_KEY")
+	defer os.Unsetenv("KEYSOURCE_TEST_PASS")
+
+	key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	passphrase := []byte("correct horse")
+	for _, c := range []struct {
+		source     KeySource
+		key        []byte
+		passphrase []byte
+		err        error
+	}{
+		{KeySource{Hex: "000102030405060708090a0b0c0d0e0f"}, key, nil, nil},
+		{KeySource{KeyFile: keyFile}, key, nil, nil},
+		{KeySource{KeyEnv: "KEYSOURCE_TEST_KEY"}, key, nil, nil},
+		{KeySource{PassFile: passFile}, nil, passphrase, nil},
+		{KeySource{PassEnv: "KEYSOURCE_TEST_PASS"}, nil, passphrase, nil},
+		{KeySource{}, nil, nil, ErrKeySource},
+		{KeySource{Hex: "00", KeyFile: keyFile}, nil, nil, ErrKeySource},
+		{KeySource{PassFile: emptyFile}, nil, nil, ErrPassphrase},
+		{KeySource{PassEnv: "KEYSOURCE_TEST_UNSET"}, nil, nil, ErrPassphrase},
+	} {
+		k, p, err := c.source.Read()
+		if err != c.err || !bytes.Equal(k, c.key) || !bytes.Equal(p, c.passphrase) {
+			t.Errorf("%+v: %x, %q, %v, want %x, %q, %v", c.source, k, p, err, c.key, c.passphrase, c.err)
+		}
+	}
+
+	if _, _, err := (&KeySource{Hex: "0g"}).Read(); err == nil {
+		t.Error("accepted a key that is not hex")
+	}
+	if _, _, err := (&KeySource{KeyFile: filepath.Join(dir, "missing")}).Read(); !os.IsNotExist(err) {
+		t.Errorf("missing key file: %v, want not exist", err)
+	}
+}
@@ -0,0 +1,70 @@
+package encryptauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// The original encrypt-auth wrote the MAC-then-encrypt format with two
+// differences from MtE here:
+//
+//   - its HMAC-SHA256 hashed ipad || M || inner hash in the outer step
+//     instead of opad || inner hash, so its tags are not HMAC tags;
+//   - a 16 byte key was kEnc with an all-zero 16 byte kMac, where MtE
+//     now treats it as a master key for HKDF.
+//
+// Neither Decrypt nor Open accepts those ciphertexts. DecryptLegacy is
+// kept so files from the original tool can still be recovered; nothing
+// encrypts in that format any more.
+
+// legacyHMAC is the original tool's HMAC-SHA256, outer hash bug included.
+func legacyHMAC(kMac []byte, M []byte) []byte {
+	K := make([]byte, 64)
+	copy(K, kMac)
+	ipad := make([]byte, 64)
+	for i := range ipad {
+		ipad[i] = K[i] ^ 0x36
+	}
+	msg := append(ipad, M...)
+	inner := sha256.Sum256(msg)
+	outer := sha256.Sum256(append(msg, inner[:]...))
+	return outer[:]
+}
+
+// DecryptLegacy decrypts a ciphertext made by the original encrypt-auth
+// under a 32 byte kEnc || kMac key or a 16 byte kEnc. Every failure is
+// ErrDecryption, so it tells bad padding and a bad tag apart no more
+// than its result does; it is still not constant time, and is only meant
+// for recovering old files.
+func DecryptLegacy(key []byte, C []byte) ([]byte, error) {
+	var kEnc, kMac []byte
+	switch len(key) {
+	case 32:
+		kEnc, kMac = key[:16], key[16:]
+	case 16:
+		kEnc, kMac = key, make([]byte, 16)
+	default:
+		return nil, ErrKeySize
+	}
+
+	if len(C) < 16+32+16 || len(C)%16 != 0 {
+		return nil, ErrDecryption
+	}
+	M2 := aesCBCDecrypt(kEnc, C[16:], C[0:16])
+
+	// The padding only covers M, but never exceeds a block
+	p := int(M2[len(M2)-1])
+	if p < 1 || p > 16 {
+		return nil, ErrDecryption
+	}
+	good := 1
+	for i := len(M2) - p; i < len(M2); i++ {
+		good &= subtle.ConstantTimeByteEq(M2[i], byte(p))
+	}
+	M1 := M2[:len(M2)-p]
+	M, T := M1[:len(M1)-32], M1[len(M1)-32:]
+	if good&subtle.ConstantTimeCompare(legacyHMAC(kMac, M), T) != 1 {
+		return nil, ErrDecryption
+	}
+	return M, nil
+}
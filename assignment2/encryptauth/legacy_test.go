@@ -0,0 +1,57 @@
+package encryptauth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// legacyVectors were made by the original encrypt-auth, with its random IV
+// replaced by 00 01 .. 0f.
+var legacyVectors = []struct {
+	key        string
+	message    string
+	ciphertext string
+}{
+	{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "",
+		"000102030405060708090a0b0c0d0e0fbc87343579286ae3df34057673fd27dffc78906e913cc24f87c1e7d1b4fcef758fb13c3583087f3e6a9044b1a098a55b"},
+	{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "attack at dawn",
+		"000102030405060708090a0b0c0d0e0fa79f8b77ca1bfd251a0d1556aded9ccedf9f87dbbc165d2d923c0576da049ad50176f1adab452b3509d93b7c413244cd"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", "",
+		"000102030405060708090a0b0c0d0e0f84e8e56bc725ac18ed57221a57d026b0cc23257910bc17515c182482e4af07e43a69e1101dbf39ab6a82780da0297b76"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", "attack at dawn",
+		"000102030405060708090a0b0c0d0e0fe70739762cfb3b3fb315593bad4289fc19476b8ccd39f2ab6af2a79d9dff7e0b3f49f1b1ecfeb8f3a91cf264ca0e1a9c"},
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	for _, v := range legacyVectors {
+		key, _ := hex.DecodeString(v.key)
+		C, _ := hex.DecodeString(v.ciphertext)
+		M, err := DecryptLegacy(key, C)
+		if err != nil || !bytes.Equal(M, []byte(v.message)) {
+			t.Errorf("%d byte key, %q: DecryptLegacy = %q, %v", len(key), v.message, M, err)
+		}
+
+		// The fixed HMAC, and HKDF for 16 byte keys, reject them
+		if got, err := Decrypt(key, C); err == nil {
+			t.Errorf("%d byte key, %q: Decrypt accepted an original ciphertext as %q", len(key), v.message, got)
+		}
+
+		for i := range C {
+			modified := append([]byte{}, C...)
+			modified[i] ^= 0x01
+			if got, err := DecryptLegacy(key, modified); err != ErrDecryption {
+				t.Errorf("%d byte key, %q: flipping byte %d gave %q, %v, want %v", len(key), v.message, i, got, err, ErrDecryption)
+			}
+		}
+	}
+
+	if _, err := DecryptLegacy(make([]byte, 24), make([]byte, 64)); err != ErrKeySize {
+		t.Errorf("24 byte key: %v, want %v", err, ErrKeySize)
+	}
+	for _, n := range []int{0, 16, 48, 65} {
+		if _, err := DecryptLegacy(make([]byte, 32), make([]byte, n)); err != ErrDecryption {
+			t.Errorf("%d byte ciphertext: %v, want %v", n, err, ErrDecryption)
+		}
+	}
+}
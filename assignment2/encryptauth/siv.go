@@ -0,0 +1,147 @@
+package encryptauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// AES-SIV (RFC 5297): deterministic authenticated encryption. The
+// synthetic IV V = S2V(K1, AD, M) is both the tag and the CTR IV under
+// K2, so encrypting the same message with the same AD gives the same
+// ciphertext and nothing else is revealed.
+
+// CMAC computes CMAC (NIST SP 800-38B, RFC 4493 for AES) of M under a 16
+// byte block cipher. It is exported for encrypt-auth-chk's -integrity cmac.
+func CMAC(block cipher.Block, M []byte) []byte {
+
+	// Subkeys: L = AES(K, 0^128), K1 = dbl(L), K2 = dbl(K1)
+	L := make([]byte, 16)
+	block.Encrypt(L, L)
+	K1 := dbl(L)
+	K2 := dbl(K1)
+
+	// Split M into blocks; the last one is XORed with K1 if it is
+	// complete, otherwise padded with 10* and XORed with K2
+	n := (len(M) + 15) / 16
+	if n == 0 {
+		n = 1
+	}
+	last := make([]byte, 16)
+	if len(M) > 0 && len(M)%16 == 0 {
+		copy(last, M[(n-1)*16:])
+		xorBytes(last, K1)
+	} else {
+		rest := M[(n-1)*16:]
+		copy(last, rest)
+		last[len(rest)] = 0x80
+		xorBytes(last, K2)
+	}
+
+	X := make([]byte, 16)
+	for i := 0; i < n-1; i++ {
+		xorBytes(X, M[i*16:(i+1)*16])
+		block.Encrypt(X, X)
+	}
+	xorBytes(X, last)
+	block.Encrypt(X, X)
+	return X
+}
+
+// dbl multiplies a block by x in GF(2^128).
+func dbl(b []byte) []byte {
+	out := make([]byte, 16)
+	for i := 0; i < 15; i++ {
+		out[i] = b[i]<<1 | b[i+1]>>7
+	}
+	out[15] = b[15] << 1
+	if b[0]&0x80 != 0 {
+		out[15] ^= 0x87
+	}
+	return out
+}
+
+func xorBytes(dst []byte, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// s2v is the RFC 5297 S2V function over the strings S1..Sn, the last of
+// which is the plaintext.
+func s2v(block cipher.Block, S ...[]byte) []byte {
+	D := CMAC(block, make([]byte, 16))
+	for _, Si := range S[:len(S)-1] {
+		D = dbl(D)
+		xorBytes(D, CMAC(block, Si))
+	}
+
+	Sn := S[len(S)-1]
+	var T []byte
+	if len(Sn) >= 16 {
+		T = append([]byte{}, Sn...)
+		xorBytes(T[len(T)-16:], D)
+	} else {
+		T = dbl(D)
+		padded := make([]byte, 16)
+		copy(padded, Sn)
+		padded[len(Sn)] = 0x80
+		xorBytes(T, padded)
+	}
+	return CMAC(block, T)
+}
+
+// sivAEAD implements cipher.AEAD for AES-SIV with a single AD string.
+// It takes no nonce.
+type sivAEAD struct {
+	macBlock cipher.Block
+	ctrBlock cipher.Block
+}
+
+// newSIV splits key into the S2V key K1 and the CTR key K2.
+func newSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 && len(key) != 48 && len(key) != 64 {
+		return nil, ErrKeySize
+	}
+	macBlock, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctrBlock, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return &sivAEAD{macBlock, ctrBlock}, nil
+}
+
+func (s *sivAEAD) NonceSize() int { return 0 }
+
+func (s *sivAEAD) Overhead() int { return 16 }
+
+// ctr runs AES-CTR with V as the IV, after clearing the two bits RFC 5297
+// reserves so implementations may use 32 or 64 bit counters.
+func (s *sivAEAD) ctr(V []byte, in []byte) []byte {
+	Q := append([]byte{}, V...)
+	Q[8] &= 0x7f
+	Q[12] &= 0x7f
+	out := make([]byte, len(in))
+	cipher.NewCTR(s.ctrBlock, Q).XORKeyStream(out, in)
+	return out
+}
+
+func (s *sivAEAD) Seal(dst, nonce, plaintext, AD []byte) []byte {
+	V := s2v(s.macBlock, AD, plaintext)
+	return append(append(dst, V...), s.ctr(V, plaintext)...)
+}
+
+func (s *sivAEAD) Open(dst, nonce, ciphertext, AD []byte) ([]byte, error) {
+	if len(ciphertext) < 16 {
+		return nil, ErrInvalidMAC
+	}
+	V := ciphertext[:16]
+	M := s.ctr(V, ciphertext[16:])
+	if subtle.ConstantTimeCompare(s2v(s.macBlock, AD, M), V) != 1 {
+		return nil, ErrInvalidMAC
+	}
+	return append(dst, M...), nil
+}
@@ -0,0 +1,89 @@
+package encryptauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+// RFC 4493, section 4: AES-CMAC test vectors, all under the same key over
+// prefixes of the NIST SP 800-38A plaintext.
+var cmacVectors = []struct {
+	name    string
+	key     string
+	message string
+	tag     string
+}{
+	{"RFC 4493 AES-CMAC example 1", "2b7e151628aed2a6abf7158809cf4f3c", "",
+		"bb1d6929e95937287fa37d129b756746"},
+	{"RFC 4493 AES-CMAC example 2", "2b7e151628aed2a6abf7158809cf4f3c", nistPlaintext[0:32],
+		"070a16b46b4d4144f79bdd9dd04a287c"},
+	{"RFC 4493 AES-CMAC example 3", "2b7e151628aed2a6abf7158809cf4f3c", nistPlaintext[0:80],
+		"dfa66747de9ae63030ca32611497c827"},
+	{"RFC 4493 AES-CMAC example 4", "2b7e151628aed2a6abf7158809cf4f3c", nistPlaintext,
+		"51f0bebf7e3b9d92fc49741779363cfe"},
+}
+
+func TestCMAC(t *testing.T) {
+	for _, v := range cmacVectors {
+		key, _ := hex.DecodeString(v.key)
+		M, _ := hex.DecodeString(v.message)
+		T, _ := hex.DecodeString(v.tag)
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CMAC(block, M); !bytes.Equal(got, T) {
+			t.Errorf("%s: got %x, want %x", v.name, got, T)
+		}
+	}
+}
+
+// RFC 5297, appendix A.1: deterministic AES-SIV with one AD string.
+var sivVector = struct {
+	key        string
+	AD         string
+	plaintext  string
+	ciphertext string
+}{
+	"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+	"101112131415161718191a1b1c1d1e1f2021222324252627",
+	"112233445566778899aabbccddee",
+	"85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+}
+
+func TestSIVVector(t *testing.T) {
+	key, _ := hex.DecodeString(sivVector.key)
+	AD, _ := hex.DecodeString(sivVector.AD)
+	M, _ := hex.DecodeString(sivVector.plaintext)
+	want, _ := hex.DecodeString(sivVector.ciphertext)
+
+	aead, err := newSIV(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aead.Seal(nil, nil, M, AD); !bytes.Equal(got, want) {
+		t.Errorf("RFC 5297 A.1: got %x, want %x", got, want)
+	}
+	if got, err := aead.Open(nil, nil, want, AD); err != nil || !bytes.Equal(got, M) {
+		t.Errorf("RFC 5297 A.1: opened to %x, %v, want %x", got, err, M)
+	}
+
+	// Seal(SIV) also authenticates its header, so only its determinism
+	// and the round trip can be checked against the vector's inputs
+	C1, err := Seal(SIV, key, AD, M)
+	if err != nil {
+		t.Fatal(err)
+	}
+	C2, err := Seal(SIV, key, AD, M)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(C1, C2) || len(C1) != headerSize+len(want) {
+		t.Errorf("Seal(SIV) gave %x then %x, want the same %d bytes", C1, C2, headerSize+len(want))
+	}
+	if got, err := Open(SIV, key, AD, C1); err != nil || !bytes.Equal(got, M) {
+		t.Errorf("Open(SIV) = %x, %v, want %x", got, err, M)
+	}
+}
@@ -0,0 +1,200 @@
+package encryptauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"io"
+)
+
+// The streaming format splits the input into chunks that are sealed one
+// at a time, so neither side ever holds more than a chunk in memory:
+//
+//	stream:  header | chunk size (4) | nonce (16) | record | record | ...
+//	record:  flag (1) | length (4) | IV | AES-CBC(kEnc, IV, chunk || PS) | T
+//	T:       HMAC(kMac, AD || IV || AES-CBC(...))
+//	AD:      stream header | sequence number (8) | flag
+//
+// The nonce makes every stream's AD unique, the sequence number pins each
+// record to its position and only the last record has flag 1, so records
+// that are dropped, reordered or spliced in from another stream fail the
+// MAC, and a stream cut off after a record is missing its final flag.
+//
+// Records are Encrypt-then-MAC: the tag is checked before a record is
+// decrypted, so a modified record is only ever reported as ErrInvalidMAC
+// and its padding cannot be used as an oracle.
+
+// streamChunkSize is the default number of plaintext bytes per record,
+// streamMaxChunkSize the largest a reader will accept.
+const (
+	streamChunkSize    = 64 * 1024
+	streamMaxChunkSize = 16 * 1024 * 1024
+)
+
+const (
+	recordMore  byte = 0
+	recordFinal byte = 1
+)
+
+// recordAD returns the additional data authenticated with a record.
+func recordAD(streamHeader []byte, seq uint64, flag byte) []byte {
+	AD := make([]byte, len(streamHeader)+9)
+	copy(AD, streamHeader)
+	binary.BigEndian.PutUint64(AD[len(streamHeader):], seq)
+	AD[len(AD)-1] = flag
+	return AD
+}
+
+// encryptStream reads plaintext from r and writes the sealed stream to w.
+func encryptStream(s Suite, kEnc []byte, kMac []byte, r io.Reader, w io.Writer, chunkSize int) error {
+
+	// Stream header: format header, chunk size and a random nonce
+	streamHeader := writeHeader(formatStream)
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(chunkSize))
+	streamHeader = append(streamHeader, size...)
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	streamHeader = append(streamHeader, nonce...)
+	if _, err := w.Write(streamHeader); err != nil {
+		return err
+	}
+
+	// Read one chunk ahead so the last one can be flagged as final
+	current, err := readChunk(r, chunkSize)
+	if err != nil {
+		return err
+	}
+	for seq := uint64(0); ; seq++ {
+		next, err := readChunk(r, chunkSize)
+		if err != nil {
+			return err
+		}
+		flag := recordMore
+		if len(next) == 0 {
+			flag = recordFinal
+		}
+
+		C := sealRecord(s, kEnc, kMac, recordAD(streamHeader, seq, flag), current)
+		record := make([]byte, 5)
+		record[0] = flag
+		binary.BigEndian.PutUint32(record[1:], uint32(len(C)))
+		if _, err := w.Write(append(record, C...)); err != nil {
+			return err
+		}
+
+		if flag == recordFinal {
+			return nil
+		}
+		current = next
+	}
+}
+
+// decryptStream reads a sealed stream from r and writes the plaintext of
+// each record to w once it has been authenticated. On error, w may
+// already hold the plaintext of the records before the bad one.
+func decryptStream(s Suite, kEnc []byte, kMac []byte, r io.Reader, w io.Writer) error {
+
+	streamHeader := make([]byte, headerSize+4+16)
+	if _, err := io.ReadFull(r, streamHeader); err != nil {
+		return ErrInvalidHeader
+	}
+	if _, err := parseHeader(streamHeader, formatStream); err != nil {
+		return err
+	}
+	chunkSize := binary.BigEndian.Uint32(streamHeader[headerSize:])
+	if chunkSize == 0 || chunkSize > streamMaxChunkSize {
+		return ErrInvalidHeader
+	}
+
+	// A sealed chunk is at most IV || chunk || a full block of padding || T
+	maxRecord := uint32(16) + chunkSize + uint32(s.TagSize()) + 16
+
+	for seq := uint64(0); ; seq++ {
+		record := make([]byte, 5)
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return ErrTruncated
+			}
+			return err
+		}
+		flag := record[0]
+		length := binary.BigEndian.Uint32(record[1:])
+		if flag > recordFinal || length > maxRecord {
+			return ErrInvalidLength
+		}
+
+		C := make([]byte, length)
+		if _, err := io.ReadFull(r, C); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return ErrTruncated
+			}
+			return err
+		}
+		M, err := openRecord(s, kEnc, kMac, recordAD(streamHeader, seq, flag), C)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(M); err != nil {
+			return err
+		}
+
+		if flag == recordFinal {
+			// Nothing may follow the final record
+			if _, err := io.ReadFull(r, make([]byte, 1)); err == nil {
+				return ErrTrailingData
+			}
+			return nil
+		}
+	}
+}
+
+// sealRecord encrypts a chunk and appends the tag over AD and the
+// ciphertext.
+func sealRecord(s Suite, kEnc []byte, kMac []byte, AD []byte, M []byte) []byte {
+	M1 := append(append([]byte{}, M...), generatePaddingString(M)...)
+	C1, IV := aesCBCEncrypt(kEnc, M1)
+	C := append(IV, C1...)
+	T := s.mac(kMac, append(append([]byte{}, AD...), C...))
+	return append(C, T...)
+}
+
+// openRecord checks a record's tag and only then decrypts it. Until the
+// tag has been checked, the record's length is the only thing looked at.
+func openRecord(s Suite, kEnc []byte, kMac []byte, AD []byte, C []byte) ([]byte, error) {
+	tagSize := s.TagSize()
+	if len(C) < 16+16+tagSize || (len(C)-tagSize)%16 != 0 {
+		return nil, ErrInvalidLength
+	}
+	body, T := C[:len(C)-tagSize], C[len(C)-tagSize:]
+	T1 := s.mac(kMac, append(append([]byte{}, AD...), body...))
+	if subtle.ConstantTimeCompare(T1, T) != 1 {
+		return nil, ErrInvalidMAC
+	}
+
+	// The record is authentic, so bad padding means a broken encryptor
+	M1 := aesCBCDecrypt(kEnc, body[16:], body[0:16])
+	lastByte := int(M1[len(M1)-1])
+	if lastByte > 16 || lastByte == 0 {
+		return nil, ErrInvalidPadding
+	}
+	for i := len(M1) - lastByte; i < len(M1); i++ {
+		if int(M1[i]) != lastByte {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return M1[:len(M1)-lastByte], nil
+}
+
+// readChunk reads up to size bytes, returning a short or empty chunk at
+// the end of the input.
+func readChunk(r io.Reader, size int) ([]byte, error) {
+	chunk := make([]byte, size)
+	n, err := io.ReadFull(r, chunk)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return chunk[:n], err
+}
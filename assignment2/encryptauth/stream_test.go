@@ -0,0 +1,40 @@
+package encryptauth
+
+import (
+	"bytes"
+	"io/ioutil"
+	mrand "math/rand"
+	"testing"
+)
+
+// TestStreamRecordErrors checks that every change to a record's IV,
+// ciphertext or tag is reported as ErrInvalidMAC, so a stream gives a
+// padding oracle attack nothing to tell apart.
+func TestStreamRecordErrors(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	for _, name := range macHashNames() {
+		s, err := NewSuite(128, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := randomBytes(rng, 2*s.KeySize)
+		M := randomBytes(rng, 1+rng.Intn(100))
+		var C bytes.Buffer
+		if err := s.EncryptStream(key, bytes.NewReader(M), &C, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		// One record, after the stream header, its flag and its length
+		start := headerSize + 4 + 16 + 5
+		stream := C.Bytes()
+		for i := start; i < len(stream); i++ {
+			for _, bit := range []byte{0x01, 0x80} {
+				modified := append([]byte{}, stream...)
+				modified[i] ^= bit
+				if err := s.DecryptStream(key, bytes.NewReader(modified), ioutil.Discard); err != ErrInvalidMAC {
+					t.Fatalf("%s: flipping %02x at %d of %d: %v, want %v", s, bit, i, len(stream), err, ErrInvalidMAC)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package encryptauth
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// Suite is the AES key size and HMAC hash of the CBC based modes: MtE,
+// EtM, the streaming format and the hardened decrypt path. The AEAD modes
+// take their key size from the key and ignore the suite.
+//
+// The suite is not recorded in the ciphertext; it must be the same for
+// decrypting as for encrypting.
+type Suite struct {
+	KeySize int    // AES key size in bytes: 16, 24 or 32
+	Hash    string // HMAC hash: sha256, sha384, sha512, sha3-256 or sha3-512
+}
+
+// DefaultSuite is AES-128 with HMAC-SHA256, the original construction.
+var DefaultSuite = Suite{KeySize: 16, Hash: "sha256"}
+
+// macHashes are the hashes HMAC can be computed with.
+var macHashes = map[string]func() hash.Hash{
+	"sha256":   sha256.New,
+	"sha384":   sha512.New384,
+	"sha512":   sha512.New,
+	"sha3-256": sha3.New256,
+	"sha3-512": sha3.New512,
+}
+
+// NewSuite returns the suite for an AES key size in bits and a hash name.
+func NewSuite(aesBits int, hashName string) (Suite, error) {
+	s := Suite{KeySize: aesBits / 8, Hash: strings.ToLower(hashName)}
+	if aesBits != 128 && aesBits != 192 && aesBits != 256 {
+		return s, ErrKeySize
+	}
+	if _, ok := macHashes[s.Hash]; !ok {
+		return s, ErrMode
+	}
+	return s, nil
+}
+
+// String names the suite, e.g. "AES-128 HMAC-SHA256".
+func (s Suite) String() string {
+	return fmt.Sprintf("AES-%d HMAC-%s", s.KeySize*8, strings.ToUpper(s.Hash))
+}
+
+// TagSize is the length of the suite's HMAC tags.
+func (s Suite) TagSize() int {
+	return macHashes[s.Hash]().Size()
+}
+
+func (s Suite) mac(kMac []byte, M []byte) []byte {
+	return hmacHash(macHashes[s.Hash], kMac, M)
+}
+
+// splitKey returns the encryption and MAC keys. A key of twice the AES
+// key size is kEnc || kMac. Any other key of at least 16 bytes is a
+// master key both are derived from with HKDF. The original tool used a
+// 16 byte key as kEnc with an all-zero kMac instead, so what it encrypted
+// under a 16 byte key only opens with DecryptLegacy.
+func (s Suite) splitKey(key []byte) ([]byte, []byte, error) {
+	if _, ok := macHashes[s.Hash]; !ok {
+		return nil, nil, ErrMode
+	}
+	if s.KeySize != 16 && s.KeySize != 24 && s.KeySize != 32 {
+		return nil, nil, ErrKeySize
+	}
+	switch {
+	case len(key) == 2*s.KeySize:
+		return key[:s.KeySize], key[s.KeySize:], nil
+	case len(key) >= 16:
+		kEnc, kMac := s.subkeys(key)
+		return kEnc, kMac, nil
+	}
+	return nil, nil, ErrKeySize
+}
+
+// subkeys derives independent encryption and MAC keys from a master key
+// with HKDF-SHA256. The MAC key is as long as the tag.
+func (s Suite) subkeys(master []byte) ([]byte, []byte) {
+	kEnc := make([]byte, s.KeySize)
+	kMac := make([]byte, s.TagSize())
+	r := hkdf.New(sha256.New, master, nil, []byte("encrypt-auth subkeys "+s.String()))
+	io.ReadFull(r, kEnc)
+	io.ReadFull(r, kMac)
+	return kEnc, kMac
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/paddingoracle"
+)
+
+var usage = `
+flip-chk [flags] -i <ciphertext file> -o <output file> -at <offset> -old <text> -new <text>
+
+Rewrites the known plaintext -old at byte -at of an encrypt-auth-chk
+message into -new, of the same length, without the key. AES-CTR lets the
+text be changed by XORing old ⊕ new into the ciphertext, and the checksum
+is fixed up to match: for xor and crc32 that needs nothing else, for sum
+only when the two texts add up to the same value. Otherwise -oracle finds
+the fix by trying all of its values on decrypt-test-chk or an HTTP
+target. A ciphertext under a MAC cannot be flipped.
+
+Flags:
+`
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+func main() {
+
+	filePtr := flag.String("i", "", "input file")
+	outPtr := flag.String("o", "", "output file")
+	atPtr := flag.Int("at", 0, "offset of the known text in the message")
+	oldPtr := flag.String("old", "", "known text")
+	newPtr := flag.String("new", "", "replacement text, the same length")
+	integrityPtr := flag.String("integrity", "", "checksum the target uses: sum, xor or crc32; by default the one in the ciphertext's header, or sum without one")
+	oraclePtr := flag.String("oracle", "none", "oracle for fixes the model cannot work out: none, command or http")
+	urlPtr := flag.String("url", "http://localhost:8081/decrypt", "endpoint for the http oracle")
+	invalidPtr := flag.Int("invalid", 400, "HTTP status meaning invalid checksum")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *filePtr == "" || *outPtr == "" || *oldPtr == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	C, err := ioutil.ReadFile(*filePtr)
+	check(err)
+
+	integrity, _, ok := checksum.SplitHeader(C)
+	if *integrityPtr != "" || !ok {
+		name := *integrityPtr
+		if name == "" {
+			name = "sum"
+		}
+		integrity, err = checksum.ParseIntegrity(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if integrity.Keyed() {
+		log.Fatal(checksum.ErrKeyed)
+	}
+	m := integrity.Model
+
+	var oracle paddingoracle.Oracle
+	switch *oraclePtr {
+	case "none":
+	case "command":
+		oracle = &paddingoracle.CommandOracle{
+			Name:    "./decrypt-test-chk",
+			Args:    []string{"-i={}", "-integrity=" + m.Name()},
+			Invalid: "INVALID CHECKSUM",
+		}
+	case "http":
+		oracle = &paddingoracle.HTTPOracle{URL: *urlPtr, Invalid: *invalidPtr}
+	default:
+		log.Fatalf("unknown oracle %q", *oraclePtr)
+	}
+
+	forged, err := checksum.Flip(oracle, m, C, *atPtr, []byte(*oldPtr), []byte(*newPtr))
+	if err != nil {
+		log.Fatal(err)
+	}
+	check(ioutil.WriteFile(*outPtr, forged, 0644))
+}
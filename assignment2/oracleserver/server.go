@@ -0,0 +1,137 @@
+// Package oracleserver serves a decryptor over HTTP, so that the padding
+// oracle and checksum attacks can be run end to end against something
+// that looks like a network service instead of a local command. Each
+// request POSTs a ciphertext and the response status says how decryption
+// went, which is exactly the leak the attacks need, unless the server is
+// told to be safe.
+package oracleserver
+
+import (
+	"io/ioutil"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxSize is the largest ciphertext accepted when Server.MaxSize is 0.
+const MaxSize = 16 << 20
+
+// SafeBody is the body of every failed response from a safe server.
+const SafeBody = "DECRYPTION FAILED"
+
+// Server is an http.Handler that decrypts the body of each POST.
+type Server struct {
+	// Decrypt decrypts C and returns nil on success.
+	Decrypt func(C []byte) error
+
+	// Status maps an error from Decrypt to the response status. The body
+	// is the error's text.
+	Status func(err error) int
+
+	// Safe answers every failed decryption with 400 and SafeBody, so that
+	// the response no longer tells one failure from another.
+	Safe bool
+
+	// Rate is how many requests per second each client may make, with
+	// bursts of up to Burst. Requests over the limit get 429 and a
+	// Retry-After header. Zero means no limit.
+	Rate  float64
+	Burst int
+
+	// MaxSize bounds the ciphertext length, MaxSize if 0.
+	MaxSize int64
+
+	// Log, if set, gets a line per request.
+	Log *log.Logger
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is a token bucket for one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from client's bucket, or reports how long until one
+// is available.
+func (s *Server) allow(client string) (bool, time.Duration) {
+	if s.Rate <= 0 {
+		return true, 0
+	}
+	burst := float64(s.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets == nil {
+		s.buckets = make(map[string]*bucket)
+	}
+	now := time.Now()
+	b, ok := s.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*s.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / s.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// ServeHTTP decrypts the request body and answers 200 and SUCCESS, or the
+// status and text of the error.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	status, body, size := s.serve(w, r, client)
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+
+	if s.Log != nil {
+		s.Log.Printf("%s %d bytes: %d %s (%v)", client, size, status, body, time.Since(start).Round(time.Microsecond))
+	}
+}
+
+// serve works out the response to r.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, client string) (int, string, int) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return http.StatusMethodNotAllowed, "POST A CIPHERTEXT", 0
+	}
+	if ok, wait := s.allow(client); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return http.StatusTooManyRequests, "RATE LIMITED", 0
+	}
+
+	maxSize := s.MaxSize
+	if maxSize == 0 {
+		maxSize = MaxSize
+	}
+	C, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSize))
+	if err != nil {
+		return http.StatusRequestEntityTooLarge, "CIPHERTEXT TOO LARGE", len(C)
+	}
+
+	if err := s.Decrypt(C); err != nil {
+		if s.Safe {
+			return http.StatusBadRequest, SafeBody, len(C)
+		}
+		return s.Status(err), err.Error(), len(C)
+	}
+	return http.StatusOK, "SUCCESS", len(C)
+}
@@ -0,0 +1,127 @@
+package oracleserver
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	errPadding = errors.New("INVALID PADDING")
+	errMAC     = errors.New("INVALID MAC")
+	errOther   = errors.New("SOMETHING ELSE")
+)
+
+// testServer fails a ciphertext naming one of the errors above, and maps
+// them as decrypt-test does.
+func testServer() *Server {
+	return &Server{
+		Decrypt: func(C []byte) error {
+			for _, err := range []error{errPadding, errMAC, errOther} {
+				if string(C) == err.Error() {
+					return err
+				}
+			}
+			return nil
+		},
+		Status: func(err error) int {
+			switch err {
+			case errPadding:
+				return http.StatusBadRequest
+			case errMAC:
+				return http.StatusForbidden
+			}
+			return http.StatusInternalServerError
+		},
+	}
+}
+
+// post sends body to s from client and returns the status and body.
+func post(s *Server, client string, body string) (*httptest.ResponseRecorder, string) {
+	r := httptest.NewRequest(http.MethodPost, "/decrypt", strings.NewReader(body))
+	r.RemoteAddr = client
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	got, _ := ioutil.ReadAll(w.Body)
+	return w, string(got)
+}
+
+func TestServeStatus(t *testing.T) {
+	for _, safe := range []bool{false, true} {
+		s := testServer()
+		s.Safe = safe
+		for _, c := range []struct {
+			C, body, safeBody string
+			status, safeCode  int
+		}{
+			{"fine", "SUCCESS", "SUCCESS", http.StatusOK, http.StatusOK},
+			{errPadding.Error(), errPadding.Error(), SafeBody, http.StatusBadRequest, http.StatusBadRequest},
+			{errMAC.Error(), errMAC.Error(), SafeBody, http.StatusForbidden, http.StatusBadRequest},
+			{errOther.Error(), errOther.Error(), SafeBody, http.StatusInternalServerError, http.StatusBadRequest},
+		} {
+			status, body := c.status, c.body
+			if safe {
+				status, body = c.safeCode, c.safeBody
+			}
+			w, got := post(s, "192.0.2.1:1234", c.C)
+			if w.Code != status || got != body {
+				t.Errorf("safe %v, POST %q: %d %q, want %d %q", safe, c.C, w.Code, got, status, body)
+			}
+		}
+	}
+}
+
+func TestServeRequests(t *testing.T) {
+	s := testServer()
+	r := httptest.NewRequest(http.MethodGet, "/decrypt", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET: %d, Allow %q, want %d, POST", w.Code, w.Header().Get("Allow"), http.StatusMethodNotAllowed)
+	}
+
+	s.MaxSize = 4
+	if w, _ := post(s, "192.0.2.1:1234", "fine"); w.Code != http.StatusOK {
+		t.Errorf("POST of MaxSize bytes: %d, want %d", w.Code, http.StatusOK)
+	}
+	if w, _ := post(s, "192.0.2.1:1234", "fine!"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST over MaxSize: %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+// TestServeRate uses a rate slow enough that no token comes back during
+// the test, so each client gets exactly Burst requests.
+func TestServeRate(t *testing.T) {
+	s := testServer()
+	s.Rate, s.Burst = 0.01, 3
+	for i := 0; i < s.Burst; i++ {
+		if w, _ := post(s, "192.0.2.1:1234", errPadding.Error()); w.Code != http.StatusBadRequest {
+			t.Fatalf("request %d: %d, want %d", i+1, w.Code, http.StatusBadRequest)
+		}
+	}
+	w, body := post(s, "192.0.2.1:5678", "fine")
+	if w.Code != http.StatusTooManyRequests || body != "RATE LIMITED" {
+		t.Errorf("request %d: %d %q, want %d", s.Burst+1, w.Code, body, http.StatusTooManyRequests)
+	}
+	if after := w.Header().Get("Retry-After"); after != "100" {
+		t.Errorf("Retry-After %q, want 100", after)
+	}
+
+	// Another client has its own bucket
+	if w, _ := post(s, "192.0.2.2:1234", "fine"); w.Code != http.StatusOK {
+		t.Errorf("second client: %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// A Burst below 1 still lets one request through
+	s = testServer()
+	s.Rate = 0.01
+	if w, _ := post(s, "192.0.2.1:1234", "fine"); w.Code != http.StatusOK {
+		t.Errorf("Burst 0, first request: %d, want %d", w.Code, http.StatusOK)
+	}
+	if w, _ := post(s, "192.0.2.1:1234", "fine"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Burst 0, second request: %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
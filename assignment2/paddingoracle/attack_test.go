@@ -0,0 +1,268 @@
+package paddingoracle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+)
+
+var testKey = bytes.Repeat([]byte{0x2b, 0x7e, 0x15, 0x16}, 8)
+
+// testMessages cover an empty message, a tag alone in two blocks and
+// several blocks of text.
+var testMessages = [][]byte{
+	nil,
+	[]byte("exactly sixteen!"),
+	[]byte("The quick brown fox jumps over the lazy dog, twice over."),
+}
+
+// openOracle answers as decrypt-test does: the padding was valid unless
+// Open says it was not.
+func openOracle(mode encryptauth.Mode) OracleFunc {
+	return func(C []byte) (bool, error) {
+		_, err := encryptauth.Open(mode, testKey, nil, C)
+		return err != encryptauth.ErrInvalidPadding, nil
+	}
+}
+
+func TestDecryptMtE(t *testing.T) {
+	for _, M := range testMessages {
+		C, err := encryptauth.Seal(encryptauth.MtE, testKey, nil, M)
+		if err != nil {
+			t.Fatal(err)
+		}
+		P, err := Decrypt(openOracle(encryptauth.MtE), C)
+		if err != nil {
+			t.Fatalf("%d byte message: %v", len(M), err)
+		}
+		got, err := Unpad(P, 32)
+		if err != nil || !bytes.Equal(got, M) {
+			t.Errorf("%d byte message: recovered %q, %v, want %q", len(M), got, err, M)
+		}
+	}
+}
+
+// TestDecryptEtM wraps every guess in the ciphertext's header and tag, as
+// decrypt-attack does. The tag never matches, so Open rejects every guess
+// on the MAC and the attack must say it has no oracle.
+func TestDecryptEtM(t *testing.T) {
+	for _, M := range testMessages {
+		C, err := encryptauth.Seal(encryptauth.EtM, testKey, nil, M)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := Wrap(openOracle(encryptauth.EtM), C[:4], C[len(C)-32:])
+		P, err := Decrypt(o, C[4:len(C)-32])
+		var e *BlockError
+		if !errors.As(err, &e) || e.Err != ErrNotOracle || e.Block != 1 || e.Byte != BlockSize-1 {
+			t.Errorf("%d byte message: recovered %q, %v, want block 1 byte 15: %v", len(M), P, err, ErrNotOracle)
+		}
+	}
+}
+
+// TestWorkers checks that batching the guesses, including batches
+// larger than guessOrder, does not change the plaintext.
+func TestWorkers(t *testing.T) {
+	C, err := encryptauth.Seal(encryptauth.MtE, testKey, nil, testMessages[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := (&Attack{Oracle: openOracle(encryptauth.MtE), Workers: 1}).Decrypt(C)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 2, 8, 256, 300} {
+		got, err := (&Attack{Oracle: openOracle(encryptauth.MtE), Workers: workers}).Decrypt(C)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%d workers: %q, %v, want %q", workers, got, err, want)
+		}
+	}
+}
+
+func TestGuessOrder(t *testing.T) {
+	if len(guessOrder) != 256 {
+		t.Fatalf("%d guesses, want 256", len(guessOrder))
+	}
+	var seen [256]bool
+	for _, g := range guessOrder {
+		if seen[g] {
+			t.Fatalf("%#02x guessed twice", g)
+		}
+		seen[g] = true
+	}
+
+	// likelyBytes repeats \t, \n and \r among the padding values
+	likely := map[byte]bool{}
+	for i := 0; i < len(likelyBytes); i++ {
+		likely[likelyBytes[i]] = true
+	}
+	for i, g := range guessOrder[:len(likely)] {
+		if !likely[g] {
+			t.Errorf("guessOrder[%d] = %#02x, want likelyBytes first", i, g)
+		}
+	}
+}
+
+// aesOracle is a bare CBC padding oracle under testKey's first half, with
+// reject turning down valid padding for the attack's own purposes.
+func aesOracle(t *testing.T, reject func(P []byte) bool) OracleFunc {
+	block, err := aes.NewCipher(testKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(C []byte) (bool, error) {
+		P := make([]byte, len(C)-BlockSize)
+		cipher.NewCBCDecrypter(block, C[:BlockSize]).CryptBlocks(P, C[BlockSize:])
+		if _, err := Unpad(P, 0); err != nil {
+			return false, nil
+		}
+		return reject == nil || !reject(P), nil
+	}
+}
+
+// TestDecryptBlockPaddingTwins decrypts blocks ending in 02 w and
+// 03 03 w. 'w' ^ 3 = 't' and 'w' ^ 2 = 'u' come before 'w' in guessOrder,
+// so the last byte's first accepted guess gives 02 02 or 03 03 03 padding
+// rather than 01. Ending in 't', the genuine guess comes first.
+func TestDecryptBlockPaddingTwins(t *testing.T) {
+	block, err := aes.NewCipher(testKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, P := range []string{
+		"fourteen bytes\x02w",
+		"thirteen byt\x03\x03\x03w",
+		"padding twins:\x02t",
+	} {
+		CPrev := []byte("an IV of 16 byte")
+		C := make([]byte, BlockSize)
+		x := make([]byte, BlockSize)
+		for i := range x {
+			x[i] = P[i] ^ CPrev[i]
+		}
+		block.Encrypt(C, x)
+		got, err := DecryptBlock(aesOracle(t, nil), C, CPrev)
+		if err != nil || string(got) != P {
+			t.Errorf("DecryptBlock = %q, %v, want %q", got, err, P)
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	broken := errors.New("oracle broken")
+	C, err := encryptauth.Seal(encryptauth.MtE, testKey, nil, testMessages[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		o    Oracle
+		byte int
+		err  error
+	}{
+		{"accepts everything", OracleFunc(func([]byte) (bool, error) { return true, nil }), BlockSize - 1, ErrNotOracle},
+		{"rejects everything", OracleFunc(func([]byte) (bool, error) { return false, nil }), BlockSize - 1, ErrNoGuess},
+		{"rejects 06 padding", aesOracle(t, func(P []byte) bool { return P[len(P)-1] == 6 }), BlockSize - 6, ErrNoGuess},
+		{"rejects 10 padding", aesOracle(t, func(P []byte) bool { return P[len(P)-1] == 0x10 }), 0, ErrNoGuess},
+		{"fails", OracleFunc(func([]byte) (bool, error) { return false, broken }), BlockSize - 1, broken},
+	} {
+		_, err := DecryptBlock(c.o, C[BlockSize:2*BlockSize], C[:BlockSize])
+		var e *BlockError
+		if !errors.As(err, &e) || e.Block != 0 || e.Byte != c.byte || e.Err != c.err {
+			t.Errorf("%s: DecryptBlock: %v, want block 0 byte %d: %v", c.name, err, c.byte, c.err)
+		}
+
+		// Decrypt places the error in the first block and keeps nothing
+		P, err := Decrypt(c.o, C)
+		if !errors.As(err, &e) || e.Block != 1 || e.Byte != c.byte || !errors.Is(err, c.err) || len(P) != 0 {
+			t.Errorf("%s: Decrypt = %q, %v, want block 1 byte %d: %v", c.name, P, err, c.byte, c.err)
+		}
+	}
+}
+
+func TestDecryptLength(t *testing.T) {
+	for _, n := range []int{0, BlockSize, BlockSize + 1, 3*BlockSize - 1} {
+		if _, err := Decrypt(openOracle(encryptauth.MtE), make([]byte, n)); err != ErrLength {
+			t.Errorf("%d byte ciphertext: %v, want %v", n, err, ErrLength)
+		}
+	}
+}
+
+// TestEncryptMtE forges ciphertexts through the MtE oracle and checks
+// them with the key: the first half of testKey is MtE's AES key. The
+// padding is right, so Open gets as far as the missing tag.
+func TestEncryptMtE(t *testing.T) {
+	block, err := aes.NewCipher(testKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, M := range testMessages {
+		C, err := Encrypt(openOracle(encryptauth.MtE), M)
+		if err != nil {
+			t.Fatalf("%d byte message: %v", len(M), err)
+		}
+		P := make([]byte, len(C)-BlockSize)
+		cipher.NewCBCDecrypter(block, C[:BlockSize]).CryptBlocks(P, C[BlockSize:])
+		if want := Pad(append([]byte{}, M...)); !bytes.Equal(P, want) {
+			t.Errorf("%d byte message: forgery decrypts to %q, want %q", len(M), P, want)
+		}
+		if _, err := encryptauth.Open(encryptauth.MtE, testKey, nil, C); err == encryptauth.ErrInvalidPadding {
+			t.Errorf("%d byte message: forgery has invalid padding", len(M))
+		}
+	}
+}
+
+func TestPadUnpad(t *testing.T) {
+	for n := 0; n <= 3*BlockSize; n++ {
+		M := bytes.Repeat([]byte{'m'}, n)
+		P := Pad(append([]byte{}, M...))
+		if len(P)%BlockSize != 0 || len(P) <= n || len(P) > n+BlockSize {
+			t.Errorf("Pad of %d bytes gave %d", n, len(P))
+		}
+		if got, err := Unpad(P, 0); err != nil || !bytes.Equal(got, M) {
+			t.Errorf("Unpad(Pad) of %d bytes = %q, %v", n, got, err)
+		}
+	}
+
+	for _, c := range []struct {
+		P       string
+		tagSize int
+		want    string
+		err     error
+	}{
+		{"abc\x01", 0, "abc", nil},
+		{"abcTT\x02\x02", 2, "abc", nil},
+		{"TT\x02\x02", 2, "", nil},
+		{"", 0, "", ErrPadding},
+		{"abc\x00", 0, "", ErrPadding},
+		{"abc\x01\x02", 0, "", ErrPadding},
+		{"abc\x03\x02\x03", 0, "", ErrPadding},
+		{"abc\x11", 0, "", ErrPadding},
+		{"T\x02\x02", 2, "", ErrPadding},
+		{string(bytes.Repeat([]byte{0x11}, 17)), 0, "", ErrPadding},
+	} {
+		got, err := Unpad([]byte(c.P), c.tagSize)
+		if err != c.err || string(got) != c.want {
+			t.Errorf("Unpad(%q, %d) = %q, %v, want %q, %v", c.P, c.tagSize, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var got []byte
+	o := Wrap(OracleFunc(func(C []byte) (bool, error) {
+		got = C
+		return true, nil
+	}), []byte("head"), []byte("tail"))
+	C := []byte("body")
+	if ok, err := o.Query(C); !ok || err != nil {
+		t.Fatalf("Query = %v, %v", ok, err)
+	}
+	if string(got) != "headbodytail" || string(C) != "body" {
+		t.Errorf("sent %q from %q, want headbodytail from body", got, C)
+	}
+}
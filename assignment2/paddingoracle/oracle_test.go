@@ -0,0 +1,73 @@
+package paddingoracle
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCommandOracle(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	// Prints INVALID PADDING unless the file starts with "ok"
+	o := &CommandOracle{
+		Name:    "sh",
+		Args:    []string{"-c", `case "$(cat "$1")" in ok*) echo fine ;; *) echo INVALID PADDING; exit 1 ;; esac`, "sh", "{}"},
+		Invalid: "INVALID PADDING",
+	}
+	for _, c := range []struct {
+		C    string
+		want bool
+	}{{"ok then more", true}, {"not ok", false}, {"", false}} {
+		if got, err := o.Query([]byte(c.C)); err != nil || got != c.want {
+			t.Errorf("Query(%q) = %v, %v, want %v", c.C, got, err, c.want)
+		}
+	}
+
+	if _, err := (&CommandOracle{Name: "no-such-command-for-paddingoracle"}).Query(nil); err == nil {
+		t.Error("a missing command was not an error")
+	}
+}
+
+// TestHTTPOracle checks the status mapping and that a 429 is retried
+// rather than taken as an answer.
+func TestHTTPOracle(t *testing.T) {
+	var requests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&requests, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case n == 1:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case bytes.Equal(body, []byte("boom")):
+			http.Error(w, "broken", http.StatusInternalServerError)
+		case bytes.HasPrefix(body, []byte("bad")):
+			http.Error(w, "INVALID PADDING", http.StatusBadRequest)
+		case bytes.HasPrefix(body, []byte("mac")):
+			http.Error(w, "INVALID MAC", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	o := &HTTPOracle{URL: srv.URL, Invalid: http.StatusBadRequest}
+	for _, c := range []struct {
+		C    string
+		want bool
+	}{{"bad padding", false}, {"mac only", true}, {"fine", true}} {
+		if got, err := o.Query([]byte(c.C)); err != nil || got != c.want {
+			t.Errorf("Query(%q) = %v, %v, want %v", c.C, got, err, c.want)
+		}
+	}
+	if n := atomic.LoadInt64(&requests); n != 4 {
+		t.Errorf("%d requests, want 4 with the 429 retried", n)
+	}
+	if _, err := o.Query([]byte("boom")); err == nil {
+		t.Error("a 500 was not an error")
+	}
+}
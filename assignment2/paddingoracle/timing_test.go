@@ -0,0 +1,52 @@
+package paddingoracle
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestCalibrateDeterministic calibrates against a target whose timings
+// never vary, so both interquartile spreads are zero.
+func TestCalibrateDeterministic(t *testing.T) {
+	valid := bytes.Repeat([]byte{0x42}, 2*BlockSize)
+	o := &TimingOracle{Target: func(C []byte) (time.Duration, error) {
+		if bytes.Equal(C, valid) {
+			return 10 * time.Microsecond, nil
+		}
+		return 5 * time.Microsecond, nil
+	}}
+	if err := o.Calibrate(valid); err != nil {
+		t.Fatal(err)
+	}
+	if o.Needed != 1 || o.Samples != 1 {
+		t.Errorf("Needed %d and Samples %d, want 1 and 1", o.Needed, o.Samples)
+	}
+
+	bad := append([]byte{}, valid...)
+	bad[BlockSize-1] ^= 1
+	for _, c := range []struct {
+		C    []byte
+		want bool
+	}{{valid, true}, {bad, false}} {
+		if got, err := o.Query(c.C); err != nil || got != c.want {
+			t.Errorf("Query = %v, %v, want %v", got, err, c.want)
+		}
+	}
+}
+
+func TestTimingOracleSamples(t *testing.T) {
+	C := make([]byte, 2*BlockSize)
+	target := func(C []byte) (time.Duration, error) { return 0, nil }
+
+	o := &TimingOracle{Target: target, Samples: -1}
+	if err := o.Calibrate(C); err != ErrSamples {
+		t.Errorf("Calibrate with Samples -1: %v, want %v", err, ErrSamples)
+	}
+
+	// Calibrated, but Samples reset afterwards
+	o = &TimingOracle{Target: target, reference: C}
+	if _, err := o.Query(C); err != ErrSamples {
+		t.Errorf("Query with Samples 0: %v, want %v", err, ErrSamples)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	mrand "math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+)
+
+var usage = `
+timing-test [flags]
+
+Measures whether the time encrypt-auth takes to reject a ciphertext tells
+a bad padding from a bad MAC. Two classes of forged ciphertext are timed
+against each decrypt path, in random order:
+
+	padding   the last padding byte is zeroed, so the padding is invalid
+	mac       the first plaintext byte is flipped, so the padding is valid
+	          but the tag does not match
+
+Welch's t-test compares the two classes. |t| above 4.5 means the classes
+are distinguishable from their timing alone, i.e. the path is a timing
+padding oracle even if its errors look alike.
+
+Flags:
+`
+
+// threshold is the |t| above which the classes count as distinguishable,
+// as used by dudect.
+const threshold = 4.5
+
+// Path is a decrypt function under test.
+type Path struct {
+	Name    string
+	Decrypt func(key []byte, C []byte) ([]byte, error)
+}
+
+// Stats summarises the timings of one class.
+type Stats struct {
+	N        int
+	Mean     float64
+	Variance float64
+}
+
+// Summarise drops the slowest samples, which are mostly scheduler noise,
+// and returns the mean and variance of the rest.
+func Summarise(samples []float64, crop float64) Stats {
+	sorted := append([]float64{}, samples...)
+	sort.Float64s(sorted)
+	sorted = sorted[:int(float64(len(sorted))*(1-crop))]
+
+	s := Stats{N: len(sorted)}
+	for _, x := range sorted {
+		s.Mean += x
+	}
+	s.Mean /= float64(s.N)
+	for _, x := range sorted {
+		s.Variance += (x - s.Mean) * (x - s.Mean)
+	}
+	s.Variance /= float64(s.N - 1)
+	return s
+}
+
+// WelchT returns Welch's t statistic for the difference of two means.
+func WelchT(a Stats, b Stats) float64 {
+	return (a.Mean - b.Mean) / math.Sqrt(a.Variance/float64(a.N)+b.Variance/float64(b.N))
+}
+
+// Forge returns the two classes of bad ciphertext derived from a valid
+// MAC-then-encrypt ciphertext C of a message of length mLen.
+func Forge(C []byte, mLen int) (badPadding []byte, badMAC []byte) {
+
+	// The last plaintext byte is p, XORing p into the matching byte of
+	// the previous ciphertext block makes it 0
+	p := 16 - mLen%16
+	badPadding = append([]byte{}, C...)
+	badPadding[len(C)-17] ^= byte(p)
+
+	// Flipping an IV bit only changes the first plaintext byte
+	badMAC = append([]byte{}, C...)
+	badMAC[0] ^= 1
+	return badPadding, badMAC
+}
+
+// Measure times n decryptions of each class with the given path.
+func Measure(path Path, key []byte, classes [2][]byte, n int, rng *mrand.Rand) [2][]float64 {
+	var samples [2][]float64
+	for len(samples[0]) < n || len(samples[1]) < n {
+		class := rng.Intn(2)
+		if len(samples[class]) == n {
+			class = 1 - class
+		}
+		start := time.Now()
+		_, err := path.Decrypt(key, classes[class])
+		elapsed := time.Since(start)
+		if err == nil {
+			log.Fatalf("%s accepted a forged ciphertext", path.Name)
+		}
+		samples[class] = append(samples[class], float64(elapsed.Nanoseconds()))
+	}
+	return samples
+}
+
+func main() {
+	n := flag.Int("n", 20000, "samples per class and path")
+	size := flag.Int("size", 256, "message length in bytes")
+	crop := flag.Float64("crop", 0.05, "fraction of the slowest samples to drop")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed for the sample order")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *n < 2 || *size < 0 || *crop < 0 || *crop >= 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	key := make([]byte, 32)
+	M := make([]byte, *size)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal(err)
+	}
+	rand.Read(M)
+	C, err := encryptauth.Encrypt(key, M)
+	if err != nil {
+		log.Fatal(err)
+	}
+	badPadding, badMAC := Forge(C, len(M))
+	classes := [2][]byte{badPadding, badMAC}
+
+	paths := []Path{
+		{"decrypt", encryptauth.Decrypt},
+		{"hardened", encryptauth.DecryptHardened},
+	}
+	rng := mrand.New(mrand.NewSource(*seed))
+
+	fmt.Printf("%-10s %14s %14s %10s\n", "path", "padding (ns)", "mac (ns)", "t")
+	for _, path := range paths {
+		// Warm up caches and the allocator before measuring
+		Measure(path, key, classes, *n/10+1, rng)
+		samples := Measure(path, key, classes, *n, rng)
+
+		padding, mac := Summarise(samples[0], *crop), Summarise(samples[1], *crop)
+		t := WelchT(padding, mac)
+		verdict := "indistinguishable"
+		if math.Abs(t) > threshold {
+			verdict = "DISTINGUISHABLE"
+		}
+		fmt.Printf("%-10s %14.0f %14.0f %10.2f  %s\n", path.Name, padding.Mean, mac.Mean, t, verdict)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/ngram"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+)
+
+var usage = `
+two-time-pad [flags] <ciphertext file> <ciphertext file>...
+
+Recovers the plaintexts of encrypt-auth-chk ciphertexts that reused
+keystream: the same key with the same IV, or with counter ranges that
+overlap (see encrypt-auth-chk reuse). The ciphertexts are lined up by
+their counters, so that bytes encrypted with the same keystream byte
+fall in the same column.
+
+Each column's keystream byte is first picked to make every plaintext
+byte in it as likely in English as possible, then refined with the
+trigram scores of the plaintext around it. A column needs bytes from two
+ciphertexts or more to be guessed at all.
+
+-crib drags a guessed word across every position of every ciphertext
+and prints the positions where the keystream it implies makes the other
+plaintexts look most like English. -known fixes plaintext once placed,
+e.g. -known 0:12:attack fixes bytes 12 to 17 of the first message, and
+can be given many times. Texts are numbered from 0 in the order given and
+offsets count from the start of the message. Ciphertexts with a header
+may use any integrity check; the header says how much to leave out.
+
+Flags:
+`
+
+// maxOffset bounds how far apart, in blocks, two ciphertexts' counters
+// may be and still be lined up.
+const maxOffset = 1 << 20
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+// text is one ciphertext: the encrypted message, past the IV and any
+// checksum and before any tag, and where in the shared keystream it
+// starts.
+type text struct {
+	name   string
+	offset int
+	body   []byte
+}
+
+// at returns the ciphertext byte at keystream position p, if the text
+// covers it.
+func (t text) at(p int) (byte, bool) {
+	i := p - t.offset
+	if i < 0 || i >= len(t.body) {
+		return 0, false
+	}
+	return t.body[i], true
+}
+
+// known is plaintext placed with -known.
+type known struct {
+	text   int
+	offset int
+	plain  []byte
+}
+
+// knownFlag collects -known values.
+type knownFlag []known
+
+func (k *knownFlag) String() string {
+	return fmt.Sprint(*k)
+}
+
+func (k *knownFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("want text:offset:plaintext, got %q", value)
+	}
+	t, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	*k = append(*k, known{t, offset, []byte(parts[2])})
+	return nil
+}
+
+// readTexts reads the ciphertexts and lines them up by counter. The
+// header says how much checksum to skip or tag to drop; without one, skip
+// bytes are skipped.
+func readTexts(files []string, skip int) []text {
+	twoTo128 := new(big.Int).Lsh(big.NewInt(1), 128)
+	half := new(big.Int).Rsh(twoTo128, 1)
+
+	var base *big.Int
+	texts := make([]text, 0, len(files))
+	minOffset := 0
+	for _, name := range files {
+		C, err := ioutil.ReadFile(name)
+		check(err)
+		integrity, C, ok := checksum.SplitHeader(C)
+		start, tag := skip, 0
+		if ok && integrity.Keyed() {
+			start, tag = 0, integrity.Size
+		} else if ok {
+			start = integrity.Size
+		}
+		if len(C) < 16+start+tag {
+			log.Fatalf("%s: shorter than the IV", name)
+		}
+		counter := new(big.Int).SetBytes(C[0:16])
+		if base == nil {
+			base = counter
+		}
+
+		// Blocks from the first text's counter, either way round
+		d := new(big.Int).Sub(counter, base)
+		d.Mod(d, twoTo128)
+		if d.Cmp(half) >= 0 {
+			d.Sub(d, twoTo128)
+		}
+		if d.CmpAbs(big.NewInt(maxOffset)) > 0 {
+			log.Fatalf("%s: counter too far from %s's to share keystream", name, files[0])
+		}
+		offset := 16*int(d.Int64()) + start
+		if offset < minOffset {
+			minOffset = offset
+		}
+		texts = append(texts, text{name, offset, C[16+start : len(C)-tag]})
+	}
+	for i := range texts {
+		texts[i].offset -= minOffset
+	}
+	return texts
+}
+
+// solver holds the keystream recovered so far.
+type solver struct {
+	texts     []text
+	model     *ngram.Model
+	keystream []byte
+	found     []bool
+	locked    []bool
+}
+
+func newSolver(texts []text, model *ngram.Model) *solver {
+	n := 0
+	for _, t := range texts {
+		if end := t.offset + len(t.body); end > n {
+			n = end
+		}
+	}
+	return &solver{texts, model, make([]byte, n), make([]bool, n), make([]bool, n)}
+}
+
+// column returns the ciphertext bytes at keystream position p.
+func (s *solver) column(p int) []byte {
+	col := make([]byte, 0, len(s.texts))
+	for _, t := range s.texts {
+		if c, ok := t.at(p); ok {
+			col = append(col, c)
+		}
+	}
+	return col
+}
+
+// guessColumns picks each unlocked keystream byte that makes its column
+// of plaintext bytes most likely.
+func (s *solver) guessColumns() {
+	for p := range s.keystream {
+		col := s.column(p)
+		if s.locked[p] || len(col) < 2 {
+			continue
+		}
+		best, bestScore := 0, 0.0
+		for k := 0; k < 256; k++ {
+			score := 0.0
+			for _, c := range col {
+				score += ngram.ByteScore(c ^ byte(k))
+			}
+			if k == 0 || score > bestScore {
+				best, bestScore = k, score
+			}
+		}
+		s.keystream[p], s.found[p] = byte(best), true
+	}
+}
+
+// window returns text t's plaintext around keystream position p, up to
+// reach bytes either side. It stops at the first byte either way that
+// the text does not cover or whose keystream is not found, so that no
+// trigram is scored across a gap; it is empty if p itself is one.
+func (s *solver) window(t text, p int, reach int) []byte {
+	known := func(q int) bool {
+		_, ok := t.at(q)
+		return ok && s.found[q]
+	}
+	if !known(p) {
+		return nil
+	}
+	from, to := p, p+1
+	for from > p-reach && known(from-1) {
+		from--
+	}
+	for to < p+reach+1 && known(to) {
+		to++
+	}
+	w := make([]byte, 0, to-from)
+	for q := from; q < to; q++ {
+		c, _ := t.at(q)
+		w = append(w, c^s.keystream[q])
+	}
+	return w
+}
+
+// refine revisits every unlocked keystream byte with the trigram scores
+// of the plaintexts two bytes either side, keeping the best.
+func (s *solver) refine(passes int) {
+	for pass := 0; pass < passes; pass++ {
+		for p := range s.keystream {
+			if s.locked[p] || !s.found[p] {
+				continue
+			}
+			best, bestScore := s.keystream[p], 0.0
+			for k := 0; k < 256; k++ {
+				s.keystream[p] = byte(k)
+				score := 0.0
+				for _, t := range s.texts {
+					score += s.model.Fitness(s.window(t, p, 2))
+				}
+				if k == 0 || score > bestScore {
+					best, bestScore = byte(k), score
+				}
+			}
+			s.keystream[p] = best
+		}
+	}
+}
+
+// place fixes the keystream under plaintext known to be at an offset into
+// a text's message.
+func (s *solver) place(k known) error {
+	if k.text < 0 || k.text >= len(s.texts) {
+		return fmt.Errorf("no text %d", k.text)
+	}
+	t := s.texts[k.text]
+	off := k.offset
+	if off < 0 || off+len(k.plain) > len(t.body) {
+		return fmt.Errorf("%q at %d does not fit in %s", k.plain, k.offset, t.name)
+	}
+	for i, b := range k.plain {
+		p := t.offset + off + i
+		s.keystream[p], s.found[p], s.locked[p] = t.body[off+i]^b, true, true
+	}
+	return nil
+}
+
+// cribResult is one position of a dragged crib.
+type cribResult struct {
+	text      int
+	offset    int
+	score     float64
+	fragments [][]byte
+}
+
+// drag tries crib at every position of every text and returns the top
+// results by how English the other texts' fragments under the implied
+// keystream look, per byte.
+func (s *solver) drag(crib []byte, top int) []cribResult {
+	var results []cribResult
+	for ti, t := range s.texts {
+		for off := 0; off+len(crib) <= len(t.body); off++ {
+			r := cribResult{text: ti, offset: off}
+			scored := 0
+			for oi, o := range s.texts {
+				if oi == ti {
+					continue
+				}
+				fragment := make([]byte, 0, len(crib))
+				for i := range crib {
+					p := t.offset + off + i
+					if c, ok := o.at(p); ok {
+						fragment = append(fragment, c^t.body[off+i]^crib[i])
+					}
+				}
+				r.fragments = append(r.fragments, fragment)
+				r.score += s.model.Fitness(fragment)
+				scored += len(fragment)
+			}
+			if scored == 0 {
+				continue
+			}
+			r.score /= float64(scored)
+			results = append(results, r)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].score > results[j].score })
+	if len(results) > top {
+		results = results[:top]
+	}
+	return results
+}
+
+// plaintext returns text t's recovered plaintext, with '?' where the
+// keystream is unknown.
+func (s *solver) plaintext(t text) []byte {
+	M := make([]byte, 0, len(t.body))
+	for i := 0; i < len(t.body); i++ {
+		p := t.offset + i
+		if s.found[p] {
+			M = append(M, t.body[i]^s.keystream[p])
+		} else {
+			M = append(M, '?')
+		}
+	}
+	return M
+}
+
+func main() {
+
+	trigramsPtr := flag.String("trigrams", "english_trigrams.txt", "trigram counts, as in assignment1")
+	skipPtr := flag.Int("skip", 1, "bytes after the IV that are not text in ciphertexts without a header, e.g. the original checksum")
+	cribPtr := flag.String("crib", "", "word to drag across the ciphertexts")
+	topPtr := flag.Int("top", 10, "crib positions to print")
+	passesPtr := flag.Int("passes", 2, "trigram refinement passes")
+	keystreamPtr := flag.String("keystream", "", "file to write the recovered keystream to, in hex with .. for each byte not found")
+	var knownPtr knownFlag
+	flag.Var(&knownPtr, "known", "text:offset:plaintext placed in the message, may be repeated")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	model, err := ngram.Load(*trigramsPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := newSolver(readTexts(flag.Args(), *skipPtr), model)
+	for _, k := range knownPtr {
+		if err := s.place(k); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *cribPtr != "" {
+		for _, r := range s.drag([]byte(*cribPtr), *topPtr) {
+			fmt.Printf("text %d offset %d (%.2f):", r.text, r.offset, r.score)
+			for _, f := range r.fragments {
+				fmt.Printf(" %q", f)
+			}
+			fmt.Println()
+		}
+		return
+	}
+
+	s.guessColumns()
+	s.refine(*passesPtr)
+
+	for i, t := range s.texts {
+		fmt.Printf("%d %s: %q\n", i, t.name, s.plaintext(t))
+	}
+	shown := make([]string, len(s.keystream))
+	for p, k := range s.keystream {
+		if s.found[p] {
+			shown[p] = hex.EncodeToString([]byte{k})
+		} else {
+			shown[p] = ".."
+		}
+	}
+	fmt.Println("keystream:", strings.Join(shown, ""))
+	if *keystreamPtr != "" {
+		check(ioutil.WriteFile(*keystreamPtr, []byte(strings.Join(shown, "")+"\n"), 0644))
+	}
+}
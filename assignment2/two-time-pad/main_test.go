@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/ngram"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+)
+
+// counterIV returns the IV for counter n, taken mod 2^128.
+func counterIV(n *big.Int) []byte {
+	twoTo128 := new(big.Int).Lsh(big.NewInt(1), 128)
+	n = new(big.Int).Mod(n, twoTo128)
+	IV := make([]byte, 16)
+	b := n.Bytes()
+	copy(IV[16-len(b):], b)
+	return IV
+}
+
+func TestReadTexts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "two-time-pad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	header := func(name string) []byte {
+		i, err := checksum.ParseIntegrity(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checksum.Header(i)
+	}
+	body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	top := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+	type file struct {
+		header  []byte
+		counter *big.Int
+	}
+	for _, c := range []struct {
+		name    string
+		files   []file
+		offsets []int
+		bodies  []string
+	}{
+		{"same IV", []file{{nil, big.NewInt(7)}, {nil, big.NewInt(7)}},
+			[]int{1, 1}, []string{string(body[1:]), string(body[1:])}},
+		{"a block later", []file{{nil, big.NewInt(7)}, {nil, big.NewInt(8)}},
+			[]int{1, 17}, []string{string(body[1:]), string(body[1:])}},
+		// Offsets never go below 0, so the earlier text starts there
+		{"a block earlier", []file{{nil, big.NewInt(8)}, {nil, big.NewInt(7)}},
+			[]int{16, 0}, []string{string(body[1:]), string(body[1:])}},
+		{"across 2^128", []file{{nil, top}, {nil, big.NewInt(1)}},
+			[]int{1, 33}, []string{string(body[1:]), string(body[1:])}},
+		{"headers", []file{{header("crc32"), big.NewInt(7)}, {header("xor"), big.NewInt(7)}, {header("hmac-sha256"), big.NewInt(7)}},
+			[]int{4, 1, 0}, []string{string(body[4:]), string(body[1:]), string(body[:len(body)-32])}},
+	} {
+		var names []string
+		for i, f := range c.files {
+			name := filepath.Join(dir, c.name+string(rune('0'+i)))
+			C := append(append(append([]byte{}, f.header...), counterIV(f.counter)...), body...)
+			if err := ioutil.WriteFile(name, C, 0644); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, name)
+		}
+
+		for i, text := range readTexts(names, 1) {
+			if text.name != names[i] || text.offset != c.offsets[i] || string(text.body) != c.bodies[i] {
+				t.Errorf("%s: text %d at %d, %q, want %d, %q", c.name, i, text.offset, text.body, c.offsets[i], c.bodies[i])
+			}
+		}
+	}
+}
+
+// testSolver enciphers two texts with one keystream, the second three
+// bytes further along it.
+func testSolver(t *testing.T) (*solver, []byte, [][]byte) {
+	model, err := ngram.Load("../../assignment1/english_trigrams.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := [][]byte{
+		[]byte("the secret meeting is at noon by the old bridge over the river"),
+		[]byte("we attack the northern gate at dawn tomorrow with every man"),
+	}
+	keystream := make([]byte, 64)
+	for i := range keystream {
+		keystream[i] = byte(i*151 + 7)
+	}
+	var texts []text
+	for i, P := range plain {
+		offset := 3 * i
+		body := make([]byte, len(P))
+		for j := range P {
+			body[j] = P[j] ^ keystream[offset+j]
+		}
+		texts = append(texts, text{string(rune('a' + i)), offset, body})
+	}
+	return newSolver(texts, model), keystream, plain
+}
+
+func TestPlace(t *testing.T) {
+	s, keystream, plain := testSolver(t)
+	if err := s.place(known{1, 3, []byte("attack")}); err != nil {
+		t.Fatal(err)
+	}
+	for p := range s.keystream {
+		placed := p >= 6 && p < 12
+		if s.found[p] != placed || s.locked[p] != placed || placed && s.keystream[p] != keystream[p] {
+			t.Errorf("position %d: found %v, locked %v, %#02x, want %v, %#02x", p, s.found[p], s.locked[p], s.keystream[p], placed, keystream[p])
+		}
+	}
+
+	// The other text shows through where the keystream is placed
+	if got, want := s.plaintext(s.texts[0]), "??????cret m"; !bytes.HasPrefix(got, []byte(want)) {
+		t.Errorf("text 0 = %q, want %q...", got, want)
+	}
+	if got := s.plaintext(s.texts[1]); !bytes.Equal(got[3:9], plain[1][3:9]) {
+		t.Errorf("text 1 = %q", got)
+	}
+
+	for _, k := range []known{
+		{2, 0, []byte("a")},
+		{-1, 0, []byte("a")},
+		{0, -1, []byte("a")},
+		{1, len(plain[1]) - 2, []byte("man")},
+	} {
+		if err := s.place(k); err == nil {
+			t.Errorf("place(%d, %d, %q) succeeded", k.text, k.offset, k.plain)
+		}
+	}
+}
+
+func TestDrag(t *testing.T) {
+	s, _, plain := testSolver(t)
+	results := s.drag([]byte("attack"), 3)
+	if len(results) != 3 {
+		t.Fatalf("%d results, want 3", len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].score > results[i-1].score {
+			t.Errorf("result %d scores %.2f, above %.2f", i, results[i].score, results[i-1].score)
+		}
+	}
+
+	// With two texts the crib where it belongs in text 1 and in text 0
+	// under it show the same fragment, text 0's plaintext there
+	for _, r := range results[:2] {
+		if !(r.text == 1 && r.offset == 3 || r.text == 0 && r.offset == 6) || len(r.fragments) != 1 || !bytes.Equal(r.fragments[0], plain[0][6:12]) {
+			t.Errorf("crib position: text %d offset %d, %q, want text 1 offset 3 or text 0 offset 6, %q", r.text, r.offset, r.fragments, plain[0][6:12])
+		}
+	}
+	if results[0].text == results[1].text {
+		t.Errorf("best crib positions both in text %d", results[0].text)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	s, keystream, plain := testSolver(t)
+	copy(s.keystream, keystream)
+	for p := range s.found {
+		s.found[p] = p != 10
+	}
+	for _, c := range []struct {
+		text, p int
+		want    string
+	}{
+		{0, 5, string(plain[0][3:8])},
+		{0, 8, string(plain[0][6:10])},
+		{0, 11, string(plain[0][11:14])},
+		{0, 10, ""},
+		{0, 0, string(plain[0][0:3])},
+		{1, 4, string(plain[1][0:4])},
+		{1, 2, ""},
+	} {
+		if got := s.window(s.texts[c.text], c.p, 2); string(got) != c.want {
+			t.Errorf("text %d window at %d = %q, want %q", c.text, c.p, got, c.want)
+		}
+	}
+}
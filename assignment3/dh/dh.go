@@ -0,0 +1,284 @@
+// Package dh implements the Diffie-Hellman key agreement of assignment 3:
+// generating a group as in RFC 2631 and FIPS 186, a prime p with a prime q
+// dividing p-1 and a generator g of the order q subgroup, choosing keys
+// in it and computing shared secrets. The dh-* and elg-* tools and pc are
+// written against it.
+package dh
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// L is the default bit size for p.
+const L = 1024
+
+// M is the default bit size for q.
+const M = 160
+
+// ErrNoGroup is returned by GenerateGroup when no prime p turns up for a
+// q within the 4096·⌈L/1024⌉ tries FIPS 186 allows.
+var ErrNoGroup = errors.New("dh: no prime p found for q")
+
+// ErrNoLog is returned by DiscreteLog when g never reaches h.
+var ErrNoLog = errors.New("dh: no discrete logarithm")
+
+// ErrModulus is returned for a p below 2, which leaves no group to work
+// in: Exp without a modulus, or a division by zero.
+var ErrModulus = errors.New("dh: modulus p must be at least 2")
+
+// Group is a Diffie-Hellman group.
+type Group struct {
+	P *big.Int
+	Q *big.Int
+	G *big.Int
+}
+
+func checkModulus(p *big.Int) error {
+	if p.Cmp(big.NewInt(2)) < 0 {
+		return ErrModulus
+	}
+	return nil
+}
+
+func toHexInt(n *big.Int) string {
+	return fmt.Sprintf("%x", n)
+}
+
+// xorBytes takes two byte slices of the same length and XOR's them
+func xorBytes(b1 []byte, b2 []byte) []byte {
+
+	if len(b1) != len(b2) {
+		return nil
+	}
+
+	b3 := make([]byte, len(b1))
+
+	for i := 0; i < len(b1); i++ {
+		b3[i] = b1[i] ^ b2[i]
+	}
+	return b3
+}
+
+// Does not work for m >= 160 yet
+func generateQ(m int64) (*big.Int, *big.Int, error) {
+
+	// z1 is 2^m
+	z1 := new(big.Int).Exp(big.NewInt(2), big.NewInt(m), nil)
+
+	// z2 is 2^(m-1)
+	z2 := new(big.Int).Exp(big.NewInt(2), big.NewInt(m-1), nil)
+
+	// Select an arbitrary bit string SEED such that the length of SEED >= m
+	seed, err := rand.Int(rand.Reader, z1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seed.Add(seed, z1)
+	seedcp := seed
+
+	// Set U = 0
+	U := new(big.Int)
+
+	// U = SHA1[SEED] XOR SHA1[(SEED+1) mod 2^160 ]
+	h1 := sha1.New()
+	h2 := sha1.New()
+	io.WriteString(h1, toHexInt(seedcp))
+
+	seedcp.Add(seedcp, big.NewInt(1))
+	seedcp.Mod(seedcp, z1)
+	io.WriteString(h2, toHexInt(seedcp))
+
+	val := xorBytes(h1.Sum(nil), h2.Sum(nil))
+
+	U.SetString(hex.EncodeToString(val), 16)
+
+	// Form q from U by computing U mod (2^m) and setting the most significant bit (the 2^(m-1) bit) and the least significant bit to 1.
+	// In terms of boolean operations, q = U OR 2^(m-1) OR 1.
+
+	q := new(big.Int)
+	U.Mod(U, z1)
+
+	q.Or(U, big.NewInt(1))
+	q.Or(q, z2)
+
+	//  Note that 2^(m-1) < q < 2^m => z2 < q < z1
+	if q.Cmp(z1) != -1 && q.Cmp(z2) != 1 {
+		return nil, nil, errors.New("dh: q not in desired range")
+	}
+
+	return q, seed, nil
+}
+
+// Based on https://tools.ietf.org/html/rfc2631#ref-FIPS-186 - Generation of p and q
+func generatePQ(L int64, m int64) (*big.Int, *big.Int, error) {
+
+	// Set m' = m/160
+	m1 := int64(math.Ceil(float64(m) / 160))
+
+	// Set L'=  L/160
+	L1 := int64(math.Ceil(float64(L) / 160))
+
+	// Set N'= L/1024
+	N1 := int64(math.Ceil(float64(L) / 1024))
+
+	// Define p, q, seed
+	var p *big.Int
+	var q *big.Int
+	var seed *big.Int
+	var err error
+
+	// https://crypto.stackexchange.com/questions/1970/how-are-primes-generated-for-rsa
+	for {
+		q, seed, err = generateQ(m)
+		if err != nil {
+			return nil, nil, err
+		}
+		if q.ProbablyPrime(100) {
+			break
+		}
+	}
+
+	// If counter < (4096 * N)
+	var counter int64
+	for counter = 0; counter < (4096 * N1); counter++ {
+		// Set R = seed + 2*m' + (L' * counter)
+		R := new(big.Int)
+		R.Add(R, seed)
+		R.Add(R, big.NewInt(2*m1))
+		R.Add(R, new(big.Int).Mul(big.NewInt(L1), big.NewInt(counter)))
+
+		// Set V = 0
+		V := big.NewInt(0)
+
+		//  For i = 0 to L'-1 do
+		var i int64
+		for i = 0; i <= L1-1; i++ {
+
+			//  V = V + SHA1(R + i) * 2^(160 * i)
+			h := sha1.New()
+			io.WriteString(h, toHexInt(new(big.Int).Add(R, big.NewInt(i))))
+			temp1 := new(big.Int).SetBytes(h.Sum(nil))
+
+			temp2 := new(big.Int).Exp(big.NewInt(2), new(big.Int).Mul(big.NewInt(160), big.NewInt(i)), nil)
+
+			V.Add(V, new(big.Int).Mul(temp1, temp2))
+		}
+
+		// Set W = V mod 2^L
+		W := new(big.Int).Mod(V, new(big.Int).Exp(big.NewInt(2), big.NewInt(L), nil))
+
+		// Set X = W OR 2^(L-1)
+		X := new(big.Int).Or(W, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(L-1)), nil))
+
+		// Set p = X - (X mod (2*q)) + 1
+		p = big.NewInt(0)
+		temp1 := new(big.Int).Mod(X, new(big.Int).Mul(big.NewInt(2), q))
+		p.Sub(X, temp1)
+		p.Add(p, big.NewInt(1))
+
+		// If p > 2^(L-1) use a robust primality test to test whether p is prime
+
+		if p.Cmp(new(big.Int).Exp(big.NewInt(2), big.NewInt(L-1), nil)) == 1 {
+			if p.ProbablyPrime(5000) {
+				return p, q, nil
+			}
+		}
+	}
+	return nil, nil, ErrNoGroup
+}
+
+// GenerateGroup generates a group with an L bit p and an m bit q, trying
+// new values of q until one yields a prime p.
+func GenerateGroup(L int64, m int64) (*Group, error) {
+
+	var p, q *big.Int
+	var err error
+	for p == nil {
+		p, q, err = generatePQ(L, m)
+		if err != nil && err != ErrNoGroup {
+			return nil, err
+		}
+	}
+
+	// Let j = (p - 1)/q.
+	j := new(big.Int).Div(new(big.Int).Sub(p, big.NewInt(1)), q)
+
+	// Declare g
+	var g *big.Int
+
+	for {
+
+		// Set h = any integer, where 1 < h < p - 1 and h differs from any value previously tried.
+		h, err := rand.Int(rand.Reader, new(big.Int).Sub(p, big.NewInt(2)))
+		if err != nil {
+			return nil, err
+		}
+		h.Add(h, big.NewInt(2))
+
+		g = new(big.Int).Exp(h, j, p)
+
+		if g.Cmp(big.NewInt(1)) != 0 {
+			break
+		}
+	}
+
+	return &Group{P: p, Q: q, G: g}, nil
+}
+
+// GenerateKey picks a random secret x of as many bits as p and returns it
+// with g^x (mod p).
+func GenerateKey(p *big.Int, g *big.Int) (*big.Int, *big.Int, error) {
+
+	if err := checkModulus(p); err != nil {
+		return nil, nil, err
+	}
+
+	// Generate random number x in the range [0, 2^|p|)
+	x, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(p.BitLen())))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Compute g^x mod p
+	gx := new(big.Int).Exp(g, x, p)
+
+	return x, gx, nil
+}
+
+// SharedSecret returns the other party's g^y raised to the secret x,
+// g^(xy) (mod p).
+func SharedSecret(p *big.Int, x *big.Int, gy *big.Int) (*big.Int, error) {
+	if err := checkModulus(p); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Exp(gy, x, p), nil
+}
+
+// DiscreteLog finds the smallest x >= 1 such that g^x ≡ h mod p by trying
+// them all, which only finishes for small groups.
+func DiscreteLog(p *big.Int, g *big.Int, h *big.Int) (*big.Int, error) {
+
+	if err := checkModulus(p); err != nil {
+		return nil, err
+	}
+
+	target := new(big.Int).Mod(h, p)
+	temp := new(big.Int).Mod(g, p)
+	one := big.NewInt(1)
+	for x := big.NewInt(1); x.Cmp(p) < 0; x.Add(x, one) {
+		if temp.Cmp(target) == 0 {
+			return x, nil
+		}
+		temp.Mul(temp, g)
+		temp.Mod(temp, p)
+	}
+	return nil, ErrNoLog
+}
@@ -0,0 +1,36 @@
+package dh
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDiscreteLog(t *testing.T) {
+	// 5 generates the multiplicative group mod 23
+	p, g := big.NewInt(23), big.NewInt(5)
+	for x := int64(1); x < 22; x++ {
+		h := new(big.Int).Exp(g, big.NewInt(x), p)
+		got, err := DiscreteLog(p, g, h)
+		if err != nil || got.Int64() != x {
+			t.Errorf("DiscreteLog(23, 5, %v) = %v, %v, want %d", h, got, err, x)
+		}
+	}
+	if got, err := DiscreteLog(p, big.NewInt(1), big.NewInt(2)); err != ErrNoLog {
+		t.Errorf("DiscreteLog(23, 1, 2) = %v, %v, want %v", got, err, ErrNoLog)
+	}
+}
+
+func TestSmallModulusRejected(t *testing.T) {
+	for _, p := range []int64{-5, 0, 1} {
+		P := big.NewInt(p)
+		if _, err := DiscreteLog(P, big.NewInt(5), big.NewInt(8)); err != ErrModulus {
+			t.Errorf("DiscreteLog with p = %d: %v, want %v", p, err, ErrModulus)
+		}
+		if _, _, err := GenerateKey(P, big.NewInt(5)); err != ErrModulus {
+			t.Errorf("GenerateKey with p = %d: %v, want %v", p, err, ErrModulus)
+		}
+		if _, err := SharedSecret(P, big.NewInt(1<<40), big.NewInt(5)); err != ErrModulus {
+			t.Errorf("SharedSecret with p = %d: %v, want %v", p, err, ErrModulus)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package elgamal implements the hashed ElGamal encryption of assignment
+// 3 over a dh group: the sender picks b, derives k = SHA256(ga gb gab)
+// from decimal-formatted integers separated by single spaces, and
+// encrypts the message under k with AES-GCM, sending g^b alongside.
+package elgamal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/dh"
+)
+
+// NonceSize is the length of the GCM nonce in front of a ciphertext.
+const NonceSize = 12
+
+// ErrDecryption is returned when a ciphertext does not open under the key
+// derived for it.
+var ErrDecryption = errors.New("elgamal: decryption failed")
+
+// PublicKey is ( p, g, ga ).
+type PublicKey struct {
+	P  *big.Int
+	G  *big.Int
+	GA *big.Int
+}
+
+// SecretKey is ( p, g, a ).
+type SecretKey struct {
+	P *big.Int
+	G *big.Int
+	A *big.Int
+}
+
+// GenerateKey generates a group with an L bit p and an m bit q and a key
+// pair in it.
+func GenerateKey(L int64, m int64) (*PublicKey, *SecretKey, error) {
+	group, err := dh.GenerateGroup(L, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, ga, err := dh.GenerateKey(group.P, group.G)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PublicKey{group.P, group.G, ga}, &SecretKey{group.P, group.G, a}, nil
+}
+
+// deriveKey returns k = SHA256(ga gb gab).
+func deriveKey(ga *big.Int, gb *big.Int, gab *big.Int) []byte {
+	h := sha256.New()
+
+	// For compatibility, please encode your input to SHA256 using decimal formatted integers separated by a single space character.
+	fmt.Fprintf(h, "%d %d %d", ga, gb, gab)
+
+	return h.Sum(nil)
+}
+
+// Encrypt encrypts M to pub, returning g^b and nonce || AES-GCM(k, M).
+func Encrypt(pub *PublicKey, M []byte) (*big.Int, []byte, error) {
+
+	b, gb, err := dh.GenerateKey(pub.P, pub.G)
+	if err != nil {
+		return nil, nil, err
+	}
+	gab, err := dh.SharedSecret(pub.P, b, pub.GA)
+	if err != nil {
+		return nil, nil, err
+	}
+	k := deriveKey(pub.GA, gb, gab)
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, NonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+
+	// Append the ciphertext to the nonce
+	return gb, aesgcm.Seal(nonce, nonce, M, nil), nil
+}
+
+// Decrypt reverses Encrypt with the secret key.
+func Decrypt(sec *SecretKey, gb *big.Int, C []byte) ([]byte, error) {
+
+	if len(C) < NonceSize {
+		return nil, ErrDecryption
+	}
+	gab, err := dh.SharedSecret(sec.P, sec.A, gb)
+	if err != nil {
+		return nil, err
+	}
+	ga := new(big.Int).Exp(sec.G, sec.A, sec.P)
+	k := deriveKey(ga, gb, gab)
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	M, err := aesgcm.Open(nil, C[0:NonceSize], C[NonceSize:], nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return M, nil
+}
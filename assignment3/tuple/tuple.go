@@ -0,0 +1,224 @@
+// Package tuple reads and writes the "( a,b,c )" format the dh and
+// ElGamal tools exchange: an opening parenthesis, fields separated by
+// commas and a closing parenthesis, with any whitespace around each of
+// them. Fields are decimal integers, or hex for an ElGamal ciphertext.
+//
+// Parsing is strict: anything else in the file, a missing or empty field
+// or a field that is not what the caller asked for is an Error naming the
+// file and the field, rather than a zero value.
+package tuple
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrSyntax is returned for text that is not a parenthesised list of
+	// fields.
+	ErrSyntax = errors.New("not of the form ( a,b,c )")
+
+	// ErrCount is returned for a tuple with the wrong number of fields.
+	ErrCount = errors.New("wrong number of fields")
+
+	// ErrEmpty is returned for a field with nothing in it.
+	ErrEmpty = errors.New("empty field")
+
+	// ErrInt is returned for a field that is not a non-negative decimal
+	// integer.
+	ErrInt = errors.New("not a decimal integer")
+
+	// ErrHex is returned for a field that is not hex.
+	ErrHex = errors.New("not hex")
+
+	// ErrField is returned when writing a field that would not read back
+	// the same: empty, or containing whitespace, a comma or a parenthesis.
+	ErrField = errors.New("field cannot be written")
+)
+
+// Error is a malformed tuple.
+type Error struct {
+	// Name is the file the tuple came from, if any.
+	Name string
+
+	// Field is the field at fault, counting from 1, or 0 for the tuple as
+	// a whole.
+	Field int
+
+	// Err is one of the Err values above.
+	Err error
+
+	// Detail, if set, says more.
+	Detail string
+}
+
+func (e *Error) Error() string {
+	s := "tuple"
+	if e.Name != "" {
+		s = e.Name
+	}
+	if e.Field > 0 {
+		s += fmt.Sprintf(": field %d", e.Field)
+	}
+	s += ": " + e.Err.Error()
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	return s
+}
+
+// Unwrap returns the Err value.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Named sets the file name on err if it is an Error, for errors from a
+// Tuple's field methods, which do not know where it came from.
+func Named(err error, name string) error {
+	if e, ok := err.(*Error); ok {
+		e.Name = name
+	}
+	return err
+}
+
+// Tuple is the fields of a tuple, as text.
+type Tuple []string
+
+// Parse parses a tuple of any number of fields, trimming the whitespace
+// around each.
+func Parse(s string) (Tuple, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, &Error{Err: ErrSyntax}
+	}
+	inner := s[1 : len(s)-1]
+	if strings.ContainsAny(inner, "()") {
+		return nil, &Error{Err: ErrSyntax, Detail: "nested or extra parentheses"}
+	}
+
+	t := Tuple(strings.Split(inner, ","))
+	for i := range t {
+		t[i] = strings.TrimSpace(t[i])
+		if t[i] == "" {
+			return nil, &Error{Field: i + 1, Err: ErrEmpty}
+		}
+	}
+	return t, nil
+}
+
+// Decode parses data as a tuple of exactly n fields.
+func Decode(data []byte, n int) (Tuple, error) {
+	t, err := Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(t) != n {
+		return nil, &Error{Err: ErrCount, Detail: fmt.Sprintf("%d, want %d", len(t), n)}
+	}
+	return t, nil
+}
+
+// ReadFile reads a tuple of exactly n fields from the named file.
+func ReadFile(name string, n int) (Tuple, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Decode(data, n)
+	return t, Named(err, name)
+}
+
+// ReadInts reads a tuple of exactly n decimal integers from the named
+// file.
+func ReadInts(name string, n int) ([]*big.Int, error) {
+	t, err := ReadFile(name, n)
+	if err != nil {
+		return nil, err
+	}
+	ints, err := t.Ints()
+	return ints, Named(err, name)
+}
+
+// Int returns field i, counting from 0, as a decimal integer: digits
+// only, no sign, no base prefix, no separators.
+func (t Tuple) Int(i int) (*big.Int, error) {
+	f := t[i]
+	for j := 0; j < len(f); j++ {
+		if f[j] < '0' || f[j] > '9' {
+			return nil, &Error{Field: i + 1, Err: ErrInt, Detail: fmt.Sprintf("%q", f)}
+		}
+	}
+	v, ok := new(big.Int).SetString(f, 10)
+	if !ok {
+		return nil, &Error{Field: i + 1, Err: ErrInt, Detail: fmt.Sprintf("%q", f)}
+	}
+	return v, nil
+}
+
+// Ints returns every field as a decimal integer.
+func (t Tuple) Ints() ([]*big.Int, error) {
+	ints := make([]*big.Int, len(t))
+	for i := range t {
+		v, err := t.Int(i)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = v
+	}
+	return ints, nil
+}
+
+// Bytes returns field i, counting from 0, decoded from hex.
+func (t Tuple) Bytes(i int) ([]byte, error) {
+	b, err := hex.DecodeString(t[i])
+	if err != nil {
+		return nil, &Error{Field: i + 1, Err: ErrHex, Detail: err.Error()}
+	}
+	return b, nil
+}
+
+// Ints returns the tuple of the given integers in decimal.
+func Ints(ints ...*big.Int) Tuple {
+	t := make(Tuple, len(ints))
+	for i, v := range ints {
+		t[i] = v.String()
+	}
+	return t
+}
+
+// Hex returns b as a hex field.
+func Hex(b []byte) string {
+	return hex.EncodeToString(b)
+}
+
+// String formats t as "( a,b,c )", as the tools always have.
+func (t Tuple) String() string {
+	return "( " + strings.Join(t, ",") + " )"
+}
+
+// Encode formats t, checking first that it will parse back to the same
+// fields.
+func (t Tuple) Encode() ([]byte, error) {
+	if len(t) == 0 {
+		return nil, &Error{Err: ErrCount, Detail: "no fields"}
+	}
+	for i, f := range t {
+		if f == "" || strings.ContainsAny(f, ",() \t\r\n\v\f") {
+			return nil, &Error{Field: i + 1, Err: ErrField, Detail: fmt.Sprintf("%q", f)}
+		}
+	}
+	return []byte(t.String()), nil
+}
+
+// WriteFile writes t to the named file, replacing what was there.
+func WriteFile(name string, t Tuple) error {
+	data, err := t.Encode()
+	if err != nil {
+		return Named(err, name)
+	}
+	return ioutil.WriteFile(name, data, 0644)
+}
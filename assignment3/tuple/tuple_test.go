@@ -0,0 +1,173 @@
+package tuple
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRounds = 500
+
+var spaces = []string{"", " ", "  ", "\t", "\n", "\r\n", " \n "}
+
+// TestRoundTrips encodes and decodes random integer and hex tuples, with
+// the whitespace Parse tolerates spliced in.
+func TestRoundTrips(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	for i := 0; i < testRounds; i++ {
+		ints := make([]*big.Int, 1+rng.Intn(4))
+		for j := range ints {
+			ints[j] = new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(rng.Intn(1100))+1))
+		}
+		B := make([]byte, 1+rng.Intn(64))
+		rng.Read(B)
+		tup := append(Ints(ints...), Hex(B))
+
+		data, err := tup.Encode()
+		if err != nil {
+			t.Fatalf("encode %v: %v", tup, err)
+		}
+		got, err := Decode(data, len(tup))
+		if err != nil {
+			t.Fatalf("decode %q: %v", data, err)
+		}
+		checkInts(t, string(data), got, ints)
+		if b, err := got.Bytes(len(ints)); err != nil || !bytes.Equal(b, B) {
+			t.Fatalf("%q: hex field %x, %v, want %x", data, b, err, B)
+		}
+
+		// The same fields laid out any other way
+		pick := func() string { return spaces[rng.Intn(len(spaces))] }
+		s := pick() + "(" + pick()
+		for j, f := range tup {
+			if j > 0 {
+				s += pick() + "," + pick()
+			}
+			s += f
+		}
+		s += pick() + ")" + pick()
+		got, err = Decode([]byte(s), len(tup))
+		if err != nil {
+			t.Fatalf("decode %q: %v", s, err)
+		}
+		if got.String() != tup.String() {
+			t.Fatalf("decode %q: %v, want %v", s, got, tup)
+		}
+	}
+}
+
+// TestFiles goes through WriteFile and ReadFile, including overwriting a
+// longer file.
+func TestFiles(t *testing.T) {
+	rng := mrand.New(mrand.NewSource(1))
+	dir, err := ioutil.TempDir("", "tuple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "params.txt")
+
+	if _, err := ReadInts(name, 3); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v, want not exist", err)
+	}
+
+	// Shorter tuples after longer ones must not leave a tail behind
+	for _, n := range []int{3, 1, 2} {
+		ints := make([]*big.Int, n)
+		for j := range ints {
+			ints[j] = new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), 1024))
+		}
+		if n == 1 {
+			ints[0] = big.NewInt(7)
+		}
+		if err := WriteFile(name, Ints(ints...)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFile(name, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkInts(t, name, got, ints)
+	}
+
+	var e *Error
+	if _, err := ReadInts(name, 3); !errors.As(err, &e) || e.Err != ErrCount || e.Name != name {
+		t.Errorf("wrong count: %v, want %s: %v", err, name, ErrCount)
+	}
+	if err := WriteFile(name, Tuple{"1", "2,3"}); !errors.Is(err, ErrField) {
+		t.Errorf("writing a comma: %v, want %v", err, ErrField)
+	}
+}
+
+// TestMalformed checks each malformed tuple and field is rejected with
+// the expected error.
+func TestMalformed(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		n     int
+		field int
+		err   error
+	}{
+		{"", 3, 0, ErrSyntax},
+		{"1,2,3", 3, 0, ErrSyntax},
+		{"( 1,2,3", 3, 0, ErrSyntax},
+		{"1,2,3 )", 3, 0, ErrSyntax},
+		{"( 1,2,3 ) 4", 3, 0, ErrSyntax},
+		{"( 1,2,3 )( 4 )", 3, 0, ErrSyntax},
+		{"( (1),2,3 )", 3, 0, ErrSyntax},
+		{"( )", 1, 1, ErrEmpty},
+		{"( 1,,3 )", 3, 2, ErrEmpty},
+		{"( 1,2, )", 3, 3, ErrEmpty},
+		{"( 1,2 )", 3, 0, ErrCount},
+		{"( 1,2,3,4 )", 3, 0, ErrCount},
+		{"( 1,2,x )", 3, 3, ErrInt},
+		{"( 1,-2,3 )", 3, 2, ErrInt},
+		{"( +1,2,3 )", 3, 1, ErrInt},
+		{"( 0x1,2,3 )", 3, 1, ErrInt},
+		{"( 1_000,2,3 )", 3, 1, ErrInt},
+		{"( 1 2,3,4 )", 3, 1, ErrInt},
+	} {
+		tup, err := Decode([]byte(c.s), c.n)
+		if err == nil {
+			_, err = tup.Ints()
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Err != c.err || e.Field != c.field {
+			t.Errorf("%q: %v, want field %d: %v", c.s, err, c.field, c.err)
+		}
+	}
+
+	tup, err := Parse("( 12,0g )")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tup.Bytes(1); !errors.Is(err, ErrHex) {
+		t.Errorf("%q: %v, want %v", "0g", err, ErrHex)
+	}
+	for _, f := range []string{"", "a b", "a\n", "(a", "a)", "a,b"} {
+		if _, err := (Tuple{"1", f}).Encode(); !errors.Is(err, ErrField) {
+			t.Errorf("encode %q: %v, want %v", f, err, ErrField)
+		}
+	}
+	if _, err := (Tuple{}).Encode(); !errors.Is(err, ErrCount) {
+		t.Errorf("encode no fields: %v, want %v", err, ErrCount)
+	}
+}
+
+func checkInts(t *testing.T, name string, got Tuple, want []*big.Int) {
+	t.Helper()
+	for j, v := range want {
+		g, err := got.Int(j)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if g.Cmp(v) != 0 {
+			t.Fatalf("%s: field %d is %v, want %v", name, j+1, g, v)
+		}
+	}
+}
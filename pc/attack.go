@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/checksum"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/encryptauth"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment2/paddingoracle"
+)
+
+var attackCommand = &command{
+	name:    "attack",
+	summary: "Attacks on assignment 2's ciphertexts through a decryption oracle.",
+	sub: []*command{
+		{
+			name:     "padding-oracle",
+			synopsis: "[flags]",
+			summary: "Decrypts an encrypt-auth ciphertext from -i through a padding oracle, as decrypt-attack does.\n" +
+				"The oracle is ./decrypt-test (command), an HTTP target such as decrypt-test -serve (http), or encrypt-auth in-process with -key (local).\n" +
+				"decrypt-attack's timing oracles, -hardened targets and CBC-R encrypt are only in decrypt-attack.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				o := addOracleFlags(flags, "http://localhost:8080/decrypt", 400, true)
+				key := flags.String("key", "", "hex key for the local oracle")
+				mode := flags.String("mode", "mte", "encrypt-auth construction, mte or etm")
+				workers := flags.Int("workers", 8, "oracle queries in flight at once")
+				mac := flags.Int("mac", 32, "tag length: inside the plaintext for mte, after the ciphertext for etm")
+				in := flags.String("i", "", "ciphertext file, standard input if empty")
+				out := flags.String("o", "", "file for the recovered message, standard output if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *mode != "mte" && *mode != "etm" {
+						return usagef("-mode must be mte or etm")
+					}
+
+					var oracle paddingoracle.Oracle
+					switch o.kind {
+					case "local":
+						k, err := decodeKey(*key)
+						if err != nil {
+							return err
+						}
+						m, _ := encryptauth.ParseMode(*mode)
+						oracle = paddingoracle.OracleFunc(func(C []byte) (bool, error) {
+							_, err := encryptauth.Open(m, k, nil, C)
+							return err != encryptauth.ErrInvalidPadding, nil
+						})
+					default:
+						var err error
+						oracle, err = o.oracle("./decrypt-test", []string{"-i={}", "-mode=" + *mode}, "INVALID PADDING")
+						if err != nil {
+							return err
+						}
+					}
+
+					C, err := readInput(*in)
+					if err != nil {
+						return err
+					}
+
+					// etm guesses go out wrapped in the original header and
+					// tag, which the target rejects before the padding
+					macLength := *mac
+					if *mode == "etm" {
+						if len(C) < 4+32+macLength {
+							return paddingoracle.ErrLength
+						}
+						oracle = paddingoracle.Wrap(oracle, C[0:4], C[len(C)-macLength:])
+						C = C[4 : len(C)-macLength]
+						macLength = 0
+					}
+
+					attack := &paddingoracle.Attack{Oracle: oracle, Workers: *workers}
+					plaintext, err := attack.Decrypt(C)
+					fmt.Fprintf(os.Stderr, "%d queries\n", attack.Queries())
+					if err != nil {
+						if len(plaintext) > 0 {
+							fmt.Fprintf(os.Stderr, "recovered %d bytes before the failure: %q\n", len(plaintext), plaintext)
+						}
+						return err
+					}
+					M, err := paddingoracle.Unpad(plaintext, macLength)
+					if err != nil {
+						return fmt.Errorf("%v in %q", err, plaintext)
+					}
+					return writeOutput(*out, M)
+				}
+			},
+		},
+		{
+			name:     "checksum",
+			synopsis: "[flags]",
+			summary: "Decrypts an encrypt-auth-chk ciphertext from -i through its checksum oracle, as decrypt-attack-chk does,\n" +
+				"or shows a MAC rejecting the forgeries. The oracle is ./decrypt-test-chk (command) or an HTTP target (http).",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				o := addOracleFlags(flags, "http://localhost:8081/decrypt", 0, false)
+				integrity := flags.String("integrity", "", "integrity check the target uses; by default the one in the ciphertext's header, or sum without one")
+				in := flags.String("i", "", "ciphertext file, standard input if empty")
+				out := flags.String("o", "", "file for the recovered message, standard output if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					C, err := readInput(*in)
+					if err != nil {
+						return err
+					}
+
+					named, _, ok := checksum.SplitHeader(C)
+					if *integrity != "" || !ok {
+						name := *integrity
+						if name == "" {
+							name = "sum"
+						}
+						named, err = checksum.ParseIntegrity(name)
+						if err != nil {
+							return usagef("-integrity %s: %v", name, err)
+						}
+					}
+					invalid := "INVALID CHECKSUM"
+					if named.Keyed() {
+						invalid = "INVALID MAC"
+						if o.invalid == 0 {
+							o.invalid = 403
+						}
+					} else if o.invalid == 0 {
+						o.invalid = 400
+					}
+					oracle, err := o.oracle("./decrypt-test-chk", []string{"-i={}", "-integrity=" + named.Name}, invalid)
+					if err != nil {
+						return err
+					}
+
+					if named.Keyed() {
+						forged, queries, err := checksum.Forge(oracle, C)
+						if err == checksum.ErrResisted {
+							fmt.Fprintf(os.Stderr, "%s resists the forgery: the target rejected all %d modified ciphertexts\n", named.Name, queries-1)
+							return err
+						}
+						if err != nil {
+							return err
+						}
+						fmt.Fprintf(os.Stderr, "%s FORGED after %d queries\n", named.Name, queries)
+						return writeOutput(*out, []byte(hex.EncodeToString(forged)+"\n"))
+					}
+
+					M, err := checksum.Decrypt(oracle, named.Model, C)
+					if err != nil {
+						if len(M) > 0 {
+							fmt.Fprintf(os.Stderr, "recovered %d bytes before the failure: %q\n", len(M), M)
+						}
+						return err
+					}
+					return writeOutput(*out, M)
+				}
+			},
+		},
+	},
+}
+
+// oracleFlags are the flags every attack takes to reach its oracle.
+type oracleFlags struct {
+	kind    string
+	url     string
+	invalid int
+	rate    float64
+}
+
+func addOracleFlags(flags *flag.FlagSet, url string, invalid int, local bool) *oracleFlags {
+	o := &oracleFlags{}
+	usage := "oracle: command runs the target's client, http posts to -url"
+	if local {
+		usage += ", local decrypts in-process with -key"
+	}
+	flags.StringVar(&o.kind, "oracle", "command", usage)
+	flags.StringVar(&o.url, "url", url, "endpoint for the http oracle")
+	flags.IntVar(&o.invalid, "invalid", invalid, "HTTP status meaning the ciphertext was rejected, 0 for the target's usual")
+	flags.Float64Var(&o.rate, "rate", 0, "requests per second for the http oracle, 0 for no limit")
+	return o
+}
+
+// oracle returns the command or http oracle; command runs name with args
+// and counts output of invalid as a rejection.
+func (o *oracleFlags) oracle(name string, args []string, invalid string) (paddingoracle.Oracle, error) {
+	switch o.kind {
+	case "command":
+		return &paddingoracle.CommandOracle{Name: name, Args: args, Invalid: invalid}, nil
+	case "http":
+		return &paddingoracle.HTTPOracle{URL: o.url, Invalid: o.invalid, Rate: o.rate}, nil
+	}
+	return nil, usagef("unknown oracle %q", o.kind)
+}
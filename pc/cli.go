@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/tuple"
+)
+
+// Exit codes, the same for every command.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// command is a pc command: either a group of subcommands or a leaf that
+// runs.
+type command struct {
+	name     string
+	synopsis string
+	summary  string
+
+	// setup registers the command's flags and returns the function that
+	// runs it on the arguments left after them.
+	setup func(flags *flag.FlagSet) func(args []string) error
+
+	sub []*command
+}
+
+// usageError is returned for bad arguments, which exit with exitUsage.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{fmt.Sprintf(format, args...)}
+}
+
+// find returns c's subcommand called name.
+func (c *command) find(name string) *command {
+	for _, s := range c.sub {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// newFlags returns the flag set for a leaf command, whose usage prints
+// the synopsis and the flags to standard error.
+func (c *command) newFlags(path string) (*flag.FlagSet, func(args []string) error) {
+	flags := flag.NewFlagSet(path, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	run := c.setup(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s %s\n\n%s\n", path, c.synopsis, c.summary)
+		n := 0
+		flags.VisitAll(func(*flag.Flag) { n++ })
+		if n > 0 {
+			fmt.Fprintln(os.Stderr, "\nFlags:")
+			flags.PrintDefaults()
+		}
+	}
+	return flags, run
+}
+
+// printUsage prints a group's usage: its subcommands and their summaries.
+func (c *command) printUsage(path string) {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags] [arguments]\n\n%s\n\nCommands:\n", path, c.summary)
+	for _, s := range c.sub {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", s.name, firstLine(s.summary))
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"%s <command> -h\" for a command's flags.\n", path)
+}
+
+// help prints the usage of the command named by args under c.
+func (c *command) help(path string, args []string) int {
+	for _, name := range args {
+		s := c.find(name)
+		if s == nil {
+			fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", path, name)
+			return exitUsage
+		}
+		c, path = s, path+" "+name
+	}
+	if c.sub != nil {
+		c.printUsage(path)
+	} else {
+		flags, _ := c.newFlags(path)
+		flags.Usage()
+	}
+	return exitOK
+}
+
+// run runs c, called as path, on args and returns the exit code.
+func (c *command) run(path string, args []string) int {
+	if c.sub != nil {
+		if len(args) == 0 {
+			c.printUsage(path)
+			return exitUsage
+		}
+		switch args[0] {
+		case "-h", "-help", "--help":
+			c.printUsage(path)
+			return exitOK
+		case "help":
+			return c.help(path, args[1:])
+		}
+		s := c.find(args[0])
+		if s == nil {
+			fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", path, args[0])
+			c.printUsage(path)
+			return exitUsage
+		}
+		return s.run(path+" "+s.name, args[1:])
+	}
+
+	flags, run := c.newFlags(path)
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	err := run(flags.Args())
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+	if _, ok := err.(*usageError); ok {
+		flags.Usage()
+		return exitUsage
+	}
+	return exitFailure
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// noArgs is for commands that take everything as flags.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+	return nil
+}
+
+// Input and output default to standard input and output, also written
+// "-", so that commands can be piped together.
+
+// readInput reads the named file, or standard input for "" or "-".
+func readInput(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// writeOutput writes data to the named file, or standard output for ""
+// or "-".
+func writeOutput(name string, data []byte) error {
+	if name == "" || name == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(name, data, 0644)
+}
+
+// decodeKey decodes a hex key given on the command line.
+func decodeKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, usagef("-key is required")
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, usagef("-key is not hex: %v", err)
+	}
+	return key, nil
+}
+
+// The dh and elgamal tools exchange decimal integers, and for ElGamal a
+// hex ciphertext, as "( a,b,c )".
+
+// readTuple reads a tuple of n fields from the named file, or standard
+// input.
+func readTuple(name string, n int) (tuple.Tuple, error) {
+	data, err := readInput(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := tuple.Decode(data, n)
+	return t, tupleError(err, name)
+}
+
+// readInts reads a tuple of n decimal integers from the named file, or
+// standard input.
+func readInts(name string, n int) ([]*big.Int, error) {
+	t, err := readTuple(name, n)
+	if err != nil {
+		return nil, err
+	}
+	ints, err := t.Ints()
+	return ints, tupleError(err, name)
+}
+
+// writeTuple writes t to the named file, or standard output.
+func writeTuple(name string, t tuple.Tuple) error {
+	data, err := t.Encode()
+	if err != nil {
+		return err
+	}
+	return writeOutput(name, data)
+}
+
+// tupleError names the file, or standard input, in a tuple error.
+func tupleError(err error, name string) error {
+	if name == "" || name == "-" {
+		name = "standard input"
+	}
+	return tuple.Named(err, name)
+}
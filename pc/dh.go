@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/dh"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/tuple"
+)
+
+var dhCommand = &command{
+	name:    "dh",
+	summary: "Diffie-Hellman key agreement between Alice and Bob, as dh-alice1, dh-bob and dh-alice2 do it.",
+	sub: []*command{
+		{
+			name:     "alice1",
+			synopsis: "-secret <file> [flags]",
+			summary:  "Generates a group and Alice's key, writes ( p,g,ga ) for Bob to -o and ( p,g,a ) to -secret.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				bits := flags.Int("bits", dh.L, "bits of p")
+				qbits := flags.Int("qbits", dh.M, "bits of q, at most 160")
+				secret := flags.String("secret", "", "file to keep ( p,g,a ) in")
+				out := flags.String("o", "", "file for the message to Bob, standard output if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *secret == "" {
+						return usagef("-secret is required")
+					}
+					if *qbits < 2 || *qbits > 160 || *bits <= *qbits {
+						return usagef("-qbits must be 2 to 160 and less than -bits")
+					}
+					group, err := dh.GenerateGroup(int64(*bits), int64(*qbits))
+					if err != nil {
+						return err
+					}
+					a, ga, err := dh.GenerateKey(group.P, group.G)
+					if err != nil {
+						return err
+					}
+					if err := writeTuple(*secret, tuple.Ints(group.P, group.G, a)); err != nil {
+						return err
+					}
+					return writeTuple(*out, tuple.Ints(group.P, group.G, ga))
+				}
+			},
+		},
+		{
+			name:     "bob",
+			synopsis: "-o <file> [flags]",
+			summary:  "Reads Alice's ( p,g,ga ) from -i, writes ( gb ) back to -o and prints the shared secret gab.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				in := flags.String("i", "", "Alice's message, standard input if empty")
+				out := flags.String("o", "", "file for the message back to Alice")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *out == "" || *out == "-" {
+						return usagef("-o is required: the shared secret goes to standard output")
+					}
+					params, err := readInts(*in, 3)
+					if err != nil {
+						return err
+					}
+					p, g, ga := params[0], params[1], params[2]
+					b, gb, err := dh.GenerateKey(p, g)
+					if err != nil {
+						return err
+					}
+					if err := writeTuple(*out, tuple.Ints(gb)); err != nil {
+						return err
+					}
+					gab, err := dh.SharedSecret(p, b, ga)
+					if err != nil {
+						return err
+					}
+					fmt.Println(gab)
+					return nil
+				}
+			},
+		},
+		{
+			name:     "alice2",
+			synopsis: "-secret <file> [flags]",
+			summary:  "Reads Bob's ( gb ) from -i and Alice's ( p,g,a ) from -secret and prints the shared secret gab.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				in := flags.String("i", "", "Bob's message, standard input if empty")
+				secret := flags.String("secret", "", "file alice1 kept ( p,g,a ) in")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *secret == "" {
+						return usagef("-secret is required")
+					}
+					key, err := readInts(*secret, 3)
+					if err != nil {
+						return err
+					}
+					msg, err := readInts(*in, 1)
+					if err != nil {
+						return err
+					}
+					gab, err := dh.SharedSecret(key[0], key[2], msg[0])
+					if err != nil {
+						return err
+					}
+					fmt.Println(gab)
+					return nil
+				}
+			},
+		},
+		{
+			name:     "dlog",
+			synopsis: "[flags]",
+			summary:  "Reads ( p,g,h ) from -i and prints the x with g^x = h mod p, by brute force as dl-brute does.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				in := flags.String("i", "", "input file, standard input if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					params, err := readInts(*in, 3)
+					if err != nil {
+						return err
+					}
+					x, err := dh.DiscreteLog(params[0], params[1], params[2])
+					if err != nil {
+						return err
+					}
+					fmt.Println(x)
+					return nil
+				}
+			},
+		},
+	},
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/dh"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/elgamal"
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment3/tuple"
+)
+
+var elgamalCommand = &command{
+	name:    "elgamal",
+	summary: "Hashed ElGamal with AES-GCM, as elg-keygen, elg-encrypt and elg-decrypt do it.",
+	sub: []*command{
+		{
+			name:     "keygen",
+			synopsis: "-secret <file> [flags]",
+			summary:  "Writes a public key ( p,g,ga ) to -o and the secret key ( p,g,a ) to -secret.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				bits := flags.Int("bits", dh.L, "bits of p")
+				qbits := flags.Int("qbits", dh.M, "bits of q, at most 160")
+				secret := flags.String("secret", "", "file for the secret key")
+				out := flags.String("o", "", "file for the public key, standard output if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *secret == "" {
+						return usagef("-secret is required")
+					}
+					if *qbits < 2 || *qbits > 160 || *bits <= *qbits {
+						return usagef("-qbits must be 2 to 160 and less than -bits")
+					}
+					pub, sec, err := elgamal.GenerateKey(int64(*bits), int64(*qbits))
+					if err != nil {
+						return err
+					}
+					if err := writeTuple(*secret, tuple.Ints(sec.P, sec.G, sec.A)); err != nil {
+						return err
+					}
+					return writeTuple(*out, tuple.Ints(pub.P, pub.G, pub.GA))
+				}
+			},
+		},
+		{
+			name:     "encrypt",
+			synopsis: "-key <public key file> [flags]",
+			summary:  "Encrypts -i to the public key and writes ( gb,ciphertext ), the ciphertext in hex.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				key := flags.String("key", "", "public key file")
+				in := flags.String("i", "", "message file, standard input if empty")
+				out := flags.String("o", "", "ciphertext file, standard output if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *key == "" {
+						return usagef("-key is required")
+					}
+					k, err := readInts(*key, 3)
+					if err != nil {
+						return err
+					}
+					M, err := readInput(*in)
+					if err != nil {
+						return err
+					}
+					gb, C, err := elgamal.Encrypt(&elgamal.PublicKey{P: k[0], G: k[1], GA: k[2]}, M)
+					if err != nil {
+						return err
+					}
+					return writeTuple(*out, tuple.Tuple{gb.String(), tuple.Hex(C)})
+				}
+			},
+		},
+		{
+			name:     "decrypt",
+			synopsis: "-key <secret key file> [flags]",
+			summary:  "Decrypts a ( gb,ciphertext ) from -i with the secret key.",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				key := flags.String("key", "", "secret key file")
+				in := flags.String("i", "", "ciphertext file, standard input if empty")
+				out := flags.String("o", "", "message file, standard output if empty")
+				return func(args []string) error {
+					if err := noArgs(args); err != nil {
+						return err
+					}
+					if *key == "" {
+						return usagef("-key is required")
+					}
+					k, err := readInts(*key, 3)
+					if err != nil {
+						return err
+					}
+					t, err := readTuple(*in, 2)
+					if err != nil {
+						return err
+					}
+					gb, err := t.Int(0)
+					if err != nil {
+						return tupleError(err, *in)
+					}
+					C, err := t.Bytes(1)
+					if err != nil {
+						return tupleError(err, *in)
+					}
+					M, err := elgamal.Decrypt(&elgamal.SecretKey{P: k[0], G: k[1], A: k[2]}, gb, C)
+					if err != nil {
+						return err
+					}
+					return writeOutput(*out, M)
+				}
+			},
+		},
+	},
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ShreyasAiyar/PracticalCryptography/assignment1/enigma"
+)
+
+var enigmaCommand = &command{
+	name:     "enigma",
+	synopsis: "[flags]",
+	summary: "Encrypts or decrypts, the same thing on an Enigma, text read from -i.\n" +
+		"Everything but letters is dropped first; the defaults are the machine the hill climb attacks.",
+	setup: func(flags *flag.FlagSet) func(args []string) error {
+		rotors := flags.String("rotors", "I II IV III", "rotors, leftmost first")
+		rings := flags.String("rings", "1 1 1 16", "ring settings, 1 to 26, one per rotor")
+		positions := flags.String("positions", "A A B Q", "starting positions, one letter per rotor")
+		reflector := flags.String("reflector", "C-thin", "reflector: A, B, C, B-thin or C-thin")
+		plugboard := flags.String("plugboard", "", "plugboard pairs, e.g. \"AB CD\"")
+		in := flags.String("i", "", "input file, standard input if empty")
+		out := flags.String("o", "", "output file, standard output if empty")
+		return func(args []string) error {
+			if err := noArgs(args); err != nil {
+				return err
+			}
+			e, err := newEnigma(*rotors, *rings, *positions, *reflector, *plugboard)
+			if err != nil {
+				return usagef("%v", err)
+			}
+			text, err := readInput(*in)
+			if err != nil {
+				return err
+			}
+			return writeOutput(*out, []byte(e.EncodeString(enigma.SanitizePlaintext(string(text)))+"\n"))
+		}
+	},
+}
+
+// newEnigma checks a machine's settings and builds it.
+func newEnigma(rotors string, rings string, positions string, reflector string, plugboard string) (*enigma.Enigma, error) {
+	rotorList := strings.Fields(rotors)
+	ringList := strings.Fields(rings)
+	positionList := strings.Fields(positions)
+	if len(rotorList) < 3 {
+		return nil, errors.New("at least three rotors are needed")
+	}
+	if len(ringList) != len(rotorList) || len(positionList) != len(rotorList) {
+		return nil, fmt.Errorf("%d rotors, %d ring settings and %d positions", len(rotorList), len(ringList), len(positionList))
+	}
+	if enigma.HistoricReflectors.GetByID(reflector) == nil {
+		return nil, fmt.Errorf("unknown reflector %q", reflector)
+	}
+
+	config := make([]enigma.RotorConfig, len(rotorList))
+	for i, id := range rotorList {
+		if enigma.HistoricRotors.GetByID(id) == nil {
+			return nil, fmt.Errorf("unknown rotor %q", id)
+		}
+		ring, err := strconv.Atoi(ringList[i])
+		if err != nil || ring < 1 || ring > 26 {
+			return nil, fmt.Errorf("ring setting %q is not 1 to 26", ringList[i])
+		}
+		position := strings.ToUpper(positionList[i])
+		if len(position) != 1 || position[0] < 'A' || position[0] > 'Z' {
+			return nil, fmt.Errorf("position %q is not a letter", positionList[i])
+		}
+		config[i] = enigma.RotorConfig{ID: id, Start: position[0], Ring: ring}
+	}
+
+	p, err := enigma.ParsePlugboard(plugboard)
+	if err != nil {
+		return nil, err
+	}
+	return enigma.NewEnigma(config, reflector, *p), nil
+}
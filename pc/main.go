@@ -0,0 +1,40 @@
+// Command pc runs the assignments' everyday tools from one binary with
+// the same conventions throughout: flags before arguments, -i and -o
+// defaulting to standard input and output, errors on standard error and
+// exit status 0 for success, 1 for a failed operation such as a rejected
+// ciphertext or an attack that did not finish, and 2 for bad usage.
+//
+// It covers enciphering with Enigma, encrypt-auth's constructions,
+// Diffie-Hellman, ElGamal and the padding oracle and checksum attacks
+// through a command, HTTP or in-process oracle. The rest stay separate binaries:
+// the Enigma hill climb, rotor-recover, cycles and zygalski,
+// decrypt-attack's timing oracles, -hardened and CBC-R encrypt,
+// timing-test, flip-chk and two-time-pad.
+//
+//	pc enigma -rotors "I II IV III" -positions "A A B Q" < plain.txt
+//	pc aead encrypt -keyfile key.hex -i message.txt -o message.enc
+//	pc dh alice1 -secret alice-secret.txt -o alice-msg.txt
+//	pc elgamal keygen -secret secret-key -o public-key
+//	pc attack padding-oracle -oracle http -i c.bin
+package main
+
+import (
+	"os"
+)
+
+var pc = &command{
+	name: "pc",
+	summary: "Practical cryptography tools. Input and output default to standard input and output.\n" +
+		"Exit status is 0 on success, 1 when the operation fails and 2 for bad usage.",
+	sub: []*command{
+		enigmaCommand,
+		aeadCommand,
+		dhCommand,
+		elgamalCommand,
+		attackCommand,
+	},
+}
+
+func main() {
+	os.Exit(pc.run("pc", os.Args[1:]))
+}